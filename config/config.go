@@ -1,9 +1,12 @@
 package config
 
 import (
+	"bytes"
 	"flag"
 	"io/ioutil"
+	"net/http"
 	"os"
+	"path"
 
 	"github.com/jessevdk/go-assets"
 	"gopkg.in/yaml.v2"
@@ -12,9 +15,10 @@ import (
 var resourcesPath = "resources/"
 
 type Config struct {
-	Env      string
-	External string
-	Map      *map[string]interface{}
+	Env         string
+	External    string
+	PrintRoutes bool // 输出路由表后退出
+	Map         *map[string]interface{}
 }
 
 var GlobalConfig *Config = new(Config)
@@ -77,6 +81,76 @@ func pathExists(path string) bool {
 	return false
 }
 
+// resourceFileSystem 与 ReadFile 相同，按 外部路径 -> 项目资源路径 -> 可执行文件资源路径 的顺序查找文件
+type resourceFileSystem struct {
+	dir string
+}
+
+// ResourceFileSystem 以dir为根目录的资源文件系统，可用于 http.FileServer 及 http.ServeContent
+func ResourceFileSystem(dir string) http.FileSystem {
+	return resourceFileSystem{dir: dir}
+}
+
+func (fs resourceFileSystem) Open(name string) (http.File, error) {
+	return OpenFile(path.Join(fs.dir, path.Clean("/"+name)))
+}
+
+// OpenFile 与 ReadFile 相同的顺序查找并打开文件，返回的文件支持Seek，可用于 http.ServeContent
+func OpenFile(p string) (http.File, error) {
+	if pathExists(p) {
+		// 外部路径
+		return os.Open(p)
+	} else if pathExists("./" + resourcesPath + p) {
+		// 项目资源路径
+		return os.Open("./" + resourcesPath + p)
+	} else if localAssets != nil {
+		// 可执行文件资源路径
+		return AssetFileSystem(localAssets).Open("/" + path.Join(resourcesPath, p))
+	}
+	return nil, os.ErrNotExist
+}
+
+// AssetFileSystem 包装 go-assets 文件系统，返回的文件支持并发读取及Seek
+func AssetFileSystem(fs *assets.FileSystem) http.FileSystem {
+	return assetFileSystem{fs: fs}
+}
+
+type assetFileSystem struct {
+	fs *assets.FileSystem
+}
+
+// Open go-assets 的 Open 会修改共享文件的读取位置，因此直接查找文件并为每次打开创建独立的读取器
+func (fs assetFileSystem) Open(name string) (http.File, error) {
+	if len(fs.fs.LocalPath) != 0 {
+		return fs.fs.Open(name)
+	}
+	af, ok := fs.fs.Files[path.Clean(name)]
+	if !ok {
+		return nil, os.ErrNotExist
+	}
+	if af.IsDir() {
+		return af, nil
+	}
+	return &assetFile{File: af, reader: bytes.NewReader(af.Data)}, nil
+}
+
+type assetFile struct {
+	*assets.File
+	reader *bytes.Reader
+}
+
+func (f *assetFile) Read(data []byte) (int, error) {
+	return f.reader.Read(data)
+}
+
+func (f *assetFile) Seek(offset int64, whence int) (int64, error) {
+	return f.reader.Seek(offset, whence)
+}
+
+func (f *assetFile) Close() error {
+	return nil
+}
+
 var localAssets *assets.FileSystem
 
 // GetAssets 获取资源
@@ -93,8 +167,10 @@ func InitConfig() {
 	// s.port = flag.String("port", "", "port")
 	env := flag.String("env", "dev", "运行环境")
 	external := flag.String("external", "", "外部配置文件")
+	routes := flag.Bool("routes", false, "输出路由表后退出")
 	flag.Parse()
 	GlobalConfig.Env = *env
 	GlobalConfig.External = *external
+	GlobalConfig.PrintRoutes = *routes
 	GlobalConfig.readConfig()
 }
@@ -0,0 +1,480 @@
+package context
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+	"wataru.com/gogo/frame/servlet"
+)
+
+// Content-Type MIME of the most common data formats.
+const (
+	MIMEJSON              = "application/json"
+	MIMEHTML              = "text/html"
+	MIMEXML               = "application/xml"
+	MIMEXML2              = "text/xml"
+	MIMEPlain             = "text/plain"
+	MIMEPOSTForm          = "application/x-www-form-urlencoded"
+	MIMEMultipartPOSTForm = "multipart/form-data"
+	MIMEYAML              = "application/x-yaml"
+	MIMEYAML2             = "application/yaml"
+)
+
+// ErrUnsupportedContentType 请求的Content-Type没有对应的绑定
+var ErrUnsupportedContentType = errors.New("unsupported content type")
+
+// BindingUri 路径参数绑定，参数来自路由匹配结果而非请求本身
+type BindingUri interface {
+	Name() string
+	BindUri(map[string][]string, interface{}) error
+}
+
+// DefaultBinding 根据HTTP方法及Content-Type选择绑定，GET请求使用 Form，不支持的Content-Type返回nil
+//
+//	"application/json"                  --> JSON
+//	"application/xml", "text/xml"       --> XML
+//	"application/x-yaml"                --> YAML
+//	"application/x-www-form-urlencoded" --> Form
+//	"multipart/form-data"               --> FormMultipart
+func DefaultBinding(method, contentType string) Binding {
+	if method == http.MethodGet {
+		return Form
+	}
+	switch contentType {
+	case MIMEJSON:
+		return JSON
+	case MIMEXML, MIMEXML2:
+		return XML
+	case MIMEYAML, MIMEYAML2:
+		return YAML
+	case MIMEMultipartPOSTForm:
+		return FormMultipart
+	case MIMEPOSTForm, "":
+		return Form
+	}
+	return nil
+}
+
+type xmlBinding struct{}
+
+func (xmlBinding) Name() string {
+	return "xml"
+}
+
+func (xmlBinding) Bind(req *servlet.HttpRequest, obj interface{}) error {
+	if req == nil || req.Body == nil {
+		return fmt.Errorf("invalid request")
+	}
+	return decodeXML(req.Body, obj)
+}
+
+func (xmlBinding) BindBody(body []byte, obj interface{}) error {
+	return decodeXML(bytes.NewReader(body), obj)
+}
+
+func decodeXML(r io.Reader, obj interface{}) error {
+	if err := xml.NewDecoder(r).Decode(obj); err != nil {
+		return err
+	}
+	return validate(obj)
+}
+
+type yamlBinding struct{}
+
+func (yamlBinding) Name() string {
+	return "yaml"
+}
+
+func (yamlBinding) Bind(req *servlet.HttpRequest, obj interface{}) error {
+	if req == nil || req.Body == nil {
+		return fmt.Errorf("invalid request")
+	}
+	return decodeYAML(req.Body, obj)
+}
+
+func (yamlBinding) BindBody(body []byte, obj interface{}) error {
+	return decodeYAML(bytes.NewReader(body), obj)
+}
+
+func decodeYAML(r io.Reader, obj interface{}) error {
+	if err := yaml.NewDecoder(r).Decode(obj); err != nil {
+		return err
+	}
+	return validate(obj)
+}
+
+// formBinding 绑定查询参数及请求体中的表单参数
+type formBinding struct{}
+
+func (formBinding) Name() string {
+	return "form"
+}
+
+func (formBinding) Bind(req *servlet.HttpRequest, obj interface{}) error {
+	if err := req.ParseMultipartForm(MaxMultipartMemory); err != nil && err != http.ErrNotMultipart {
+		return err
+	}
+	if err := mapFormFiles(obj, req.Form, multipartFiles(req)); err != nil {
+		return err
+	}
+	return validate(obj)
+}
+
+// formPostBinding 仅绑定请求体中的表单参数
+type formPostBinding struct{}
+
+func (formPostBinding) Name() string {
+	return "form-urlencoded"
+}
+
+func (formPostBinding) Bind(req *servlet.HttpRequest, obj interface{}) error {
+	if err := req.ParseForm(); err != nil {
+		return err
+	}
+	if err := mapForm(obj, req.PostForm); err != nil {
+		return err
+	}
+	return validate(obj)
+}
+
+type formMultipartBinding struct{}
+
+func (formMultipartBinding) Name() string {
+	return "multipart/form-data"
+}
+
+func (formMultipartBinding) Bind(req *servlet.HttpRequest, obj interface{}) error {
+	if err := req.ParseMultipartForm(MaxMultipartMemory); err != nil {
+		return err
+	}
+	if err := mapFormFiles(obj, req.PostForm, multipartFiles(req)); err != nil {
+		return err
+	}
+	return validate(obj)
+}
+
+func multipartFiles(req *servlet.HttpRequest) map[string][]*multipart.FileHeader {
+	if req.MultipartForm == nil {
+		return nil
+	}
+	return req.MultipartForm.File
+}
+
+type queryBinding struct{}
+
+func (queryBinding) Name() string {
+	return "query"
+}
+
+func (queryBinding) Bind(req *servlet.HttpRequest, obj interface{}) error {
+	if err := mapForm(obj, req.URL.Query()); err != nil {
+		return err
+	}
+	return validate(obj)
+}
+
+type headerBinding struct{}
+
+func (headerBinding) Name() string {
+	return "header"
+}
+
+func (headerBinding) Bind(req *servlet.HttpRequest, obj interface{}) error {
+	if err := mapHeader(obj, req.Header); err != nil {
+		return err
+	}
+	return validate(obj)
+}
+
+type uriBinding struct{}
+
+func (uriBinding) Name() string {
+	return "uri"
+}
+
+func (uriBinding) BindUri(m map[string][]string, obj interface{}) error {
+	if err := mapUri(obj, m); err != nil {
+		return err
+	}
+	return validate(obj)
+}
+
+func mapForm(obj interface{}, form map[string][]string) error {
+	return mapFormFiles(obj, form, nil)
+}
+
+// mapFormFiles 绑定表单参数及上传文件，文件字段类型为 *multipart.FileHeader 或 []*multipart.FileHeader
+func mapFormFiles(obj interface{}, form map[string][]string, files map[string][]*multipart.FileHeader) error {
+	return mapping(obj, "form", valuesLookup(form), files, false)
+}
+
+func mapHeader(obj interface{}, header http.Header) error {
+	return mapping(obj, "header", headerLookup(header), nil, false)
+}
+
+func mapUri(obj interface{}, m map[string][]string) error {
+	return mapping(obj, "uri", valuesLookup(m), nil, false)
+}
+
+func valuesLookup(m map[string][]string) func(name string) ([]string, bool) {
+	return func(name string) ([]string, bool) {
+		values, ok := m[name]
+		return values, ok
+	}
+}
+
+func headerLookup(header http.Header) func(name string) ([]string, bool) {
+	return func(name string) ([]string, bool) {
+		values, ok := header[textproto.CanonicalMIMEHeaderKey(name)]
+		return values, ok
+	}
+}
+
+var (
+	timeType            = reflect.TypeOf(time.Time{})
+	durationType        = reflect.TypeOf(time.Duration(0))
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	fileHeaderType      = reflect.TypeOf(&multipart.FileHeader{})
+	fileHeadersType     = reflect.TypeOf([]*multipart.FileHeader{})
+)
+
+// mapping 按tag将lookup查找到的参数值设置到结构体字段
+// tag格式为 `form:"name,default=value"`，"-"表示忽略该字段，未设置tag的字段使用字段名(`json:"-"` 的字段除外)，
+// 未设置tag的结构体字段按其字段递归绑定；time.Time 字段可通过 time_format 指定格式，支持 unix 及 unixnano
+// explicit为true时只绑定设置了tag的字段，用于同时绑定多个来源的 Context.ShouldBindRequest
+func mapping(obj interface{}, tag string, lookup func(name string) ([]string, bool), files map[string][]*multipart.FileHeader, explicit bool) error {
+	value := reflect.ValueOf(obj)
+	if value.Kind() != reflect.Ptr || value.IsNil() || value.Elem().Kind() != reflect.Struct {
+		return errors.New("binding element must be a pointer to struct")
+	}
+	return mapStruct(value.Elem(), tag, lookup, files, explicit)
+}
+
+func mapStruct(value reflect.Value, tag string, lookup func(name string) ([]string, bool), files map[string][]*multipart.FileHeader, explicit bool) error {
+	valueType := value.Type()
+	for i := 0; i < valueType.NumField(); i++ {
+		field := valueType.Field(i)
+		if field.PkgPath != "" && !field.Anonymous {
+			continue
+		}
+		tagValue, tagged := field.Tag.Lookup(tag)
+		name, defaultValue := parseBindingTag(tagValue)
+		// `json:"-"` 的字段不使用字段名绑定，只绑定显式设置了当前tag的字段
+		if name == "-" || (!tagged && strings.Split(field.Tag.Get("json"), ",")[0] == "-") {
+			continue
+		}
+		fieldValue := value.Field(i)
+		if !tagged && isNestedStruct(field.Type) {
+			if field.Type.Kind() == reflect.Ptr {
+				if fieldValue.IsNil() {
+					if !fieldValue.CanSet() {
+						continue
+					}
+					fieldValue.Set(reflect.New(field.Type.Elem()))
+				}
+				fieldValue = fieldValue.Elem()
+			}
+			if err := mapStruct(fieldValue, tag, lookup, files, explicit); err != nil {
+				return err
+			}
+			continue
+		}
+		if field.PkgPath != "" || (explicit && !tagged) {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		switch field.Type {
+		case fileHeaderType:
+			if fhs := files[name]; len(fhs) > 0 {
+				fieldValue.Set(reflect.ValueOf(fhs[0]))
+			}
+			continue
+		case fileHeadersType:
+			if fhs := files[name]; len(fhs) > 0 {
+				fieldValue.Set(reflect.ValueOf(fhs))
+			}
+			continue
+		}
+		values, ok := lookup(name)
+		if !ok || len(values) == 0 {
+			// explicit为true时字段可能已由其他来源设置，不使用默认值覆盖
+			if defaultValue == "" || (explicit && !fieldValue.IsZero()) {
+				continue
+			}
+			values = []string{defaultValue}
+		}
+		if err := setField(fieldValue, field, values); err != nil {
+			return fmt.Errorf("field %s: %v", name, err)
+		}
+	}
+	return nil
+}
+
+func parseBindingTag(tagValue string) (name, defaultValue string) {
+	parts := strings.Split(tagValue, ",")
+	for _, opt := range parts[1:] {
+		if strings.HasPrefix(opt, "default=") {
+			defaultValue = strings.TrimPrefix(opt, "default=")
+		}
+	}
+	return parts[0], defaultValue
+}
+
+func isNestedStruct(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.Struct && t != timeType && t != fileHeaderType.Elem() && !reflect.PtrTo(t).Implements(textUnmarshalerType)
+}
+
+func setField(value reflect.Value, field reflect.StructField, values []string) error {
+	switch value.Kind() {
+	case reflect.Slice:
+		if value.Type().Elem().Kind() == reflect.Uint8 {
+			value.SetBytes([]byte(values[0]))
+			return nil
+		}
+		slice := reflect.MakeSlice(value.Type(), len(values), len(values))
+		for i, s := range values {
+			if err := setValue(slice.Index(i), field, s); err != nil {
+				return err
+			}
+		}
+		value.Set(slice)
+		return nil
+	case reflect.Array:
+		if len(values) != value.Len() {
+			return fmt.Errorf("%q is not valid value for %s", values, value.Type().String())
+		}
+		for i, s := range values {
+			if err := setValue(value.Index(i), field, s); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return setValue(value, field, values[0])
+}
+
+func setValue(value reflect.Value, field reflect.StructField, s string) error {
+	if value.Kind() == reflect.Ptr {
+		elem := reflect.New(value.Type().Elem())
+		if err := setValue(elem.Elem(), field, s); err != nil {
+			return err
+		}
+		value.Set(elem)
+		return nil
+	}
+	switch value.Type() {
+	case timeType:
+		return setTime(value, field, s)
+	case durationType:
+		if s == "" {
+			s = "0"
+		}
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		value.SetInt(int64(d))
+		return nil
+	}
+	if u, ok := value.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return u.UnmarshalText([]byte(s))
+	}
+	switch value.Kind() {
+	case reflect.String:
+		value.SetString(s)
+	case reflect.Bool:
+		if s == "" {
+			s = "false"
+		}
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		value.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if s == "" {
+			s = "0"
+		}
+		n, err := strconv.ParseInt(s, 10, value.Type().Bits())
+		if err != nil {
+			return err
+		}
+		value.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if s == "" {
+			s = "0"
+		}
+		n, err := strconv.ParseUint(s, 10, value.Type().Bits())
+		if err != nil {
+			return err
+		}
+		value.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		if s == "" {
+			s = "0"
+		}
+		n, err := strconv.ParseFloat(s, value.Type().Bits())
+		if err != nil {
+			return err
+		}
+		value.SetFloat(n)
+	case reflect.Struct, reflect.Map, reflect.Slice, reflect.Interface:
+		return json.Unmarshal([]byte(s), value.Addr().Interface())
+	default:
+		return errors.New("unsupported field type " + value.Type().String())
+	}
+	return nil
+}
+
+// setTime 解析时间，time_format 默认 RFC3339，time_utc:"1" 按UTC解析，否则按本地时区解析
+func setTime(value reflect.Value, field reflect.StructField, s string) error {
+	if s == "" {
+		value.Set(reflect.ValueOf(time.Time{}))
+		return nil
+	}
+	timeFormat := field.Tag.Get("time_format")
+	if timeFormat == "" {
+		timeFormat = time.RFC3339
+	}
+	switch timeFormat {
+	case "unix", "unixnano":
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return err
+		}
+		t := time.Unix(n, 0)
+		if timeFormat == "unixnano" {
+			t = time.Unix(0, n)
+		}
+		value.Set(reflect.ValueOf(t))
+		return nil
+	}
+	location := time.Local
+	if utc, _ := strconv.ParseBool(field.Tag.Get("time_utc")); utc {
+		location = time.UTC
+	}
+	t, err := time.ParseInLocation(timeFormat, s, location)
+	if err != nil {
+		return err
+	}
+	value.Set(reflect.ValueOf(t))
+	return nil
+}
@@ -4,9 +4,11 @@ import (
 	"bytes"
 	"container/list"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"html/template"
 	"io"
+	"io/ioutil"
 	"net"
 	"net/http"
 	"net/url"
@@ -27,6 +29,7 @@ type Response struct {
 	Code    int         `json:"code"`
 	Success bool        `json:"success"`
 	Message string      `json:"message"`
+	Status  int         `json:"-"` // HTTP状态码，为0时使用 Context.Status 设置的值，默认200
 }
 
 type PageResponse struct {
@@ -41,7 +44,10 @@ type Context struct {
 	HttpRequest  *servlet.HttpRequest
 	HttpResponse *servlet.HttpResponse
 	LocalVars    *LocalVars
+	// Params 路径参数，请求结束后由路由复用，在请求结束后仍运行的goroutine中使用时需先复制
 	Params       Params
+	// RoutePattern 匹配的路由路径，如 /user/:id，未匹配路由时为空
+	RoutePattern string
 	// queryCache use url.ParseQuery cached the param query result from c.Request.URL.Query()
 	queryCache url.Values
 
@@ -49,6 +55,19 @@ type Context struct {
 	// or PUT body parameters.
 	formCache url.Values
 	Session   *session.Session
+
+	// aborted 中间件中断请求后不再执行后续中间件及处理函数
+	aborted bool
+	// result 处理函数返回或中断请求时设置的响应结果
+	result interface{}
+	// status 响应HTTP状态码，为0时默认200
+	status int
+	// body 缓存的请求体，由 Body 读取后可重复使用
+	body []byte
+	// uploadLimit 当前请求的上传限制，为nil时使用 DefaultUploadLimit
+	uploadLimit *UploadLimit
+	// multipartErr 解析multipart表单的错误，解析失败后不再重复解析
+	multipartErr error
 }
 
 type LocalVars struct {
@@ -93,17 +112,17 @@ func (ps Params) ByName(name string) (va string) {
 }
 
 var (
-	JSON = jsonBinding{}
-	// XML           = xmlBinding{}
-	// Form          = formBinding{}
-	// Query         = queryBinding{}
-	// FormPost      = formPostBinding{}
-	// FormMultipart = formMultipartBinding{}
+	JSON          = jsonBinding{}
+	XML           = xmlBinding{}
+	Form          = formBinding{}
+	Query         = queryBinding{}
+	FormPost      = formPostBinding{}
+	FormMultipart = formMultipartBinding{}
 	// ProtoBuf      = protobufBinding{}
 	// MsgPack       = msgpackBinding{}
-	// YAML          = yamlBinding{}
-	// Uri           = uriBinding{}
-	// Header        = headerBinding{}
+	YAML   = yamlBinding{}
+	Uri    = uriBinding{}
+	Header = headerBinding{}
 )
 
 // Binding describes the interface which needs to be implemented for binding the
@@ -208,6 +227,7 @@ func (v *defaultValidator) lazyinit() {
 	v.once.Do(func() {
 		v.validate = validator.New()
 		v.validate.SetTagName("binding")
+		v.validate.RegisterTagNameFunc(fieldTagName)
 	})
 }
 
@@ -238,9 +258,29 @@ func (context *Context) Error(message string) interface{} {
 	}
 }
 
+// ErrorWithStatus 返回指定HTTP状态码的错误结果
+//     return c.ErrorWithStatus(http.StatusNotFound, "用户不存在")
+func (context *Context) ErrorWithStatus(status int, message string) interface{} {
+	return &Response{
+		Data:    nil,
+		Code:    -1,
+		Success: false,
+		Message: message,
+		Status:  status,
+	}
+}
+
+// TemplateFuncs Render 使用的模板函数
+var TemplateFuncs = template.FuncMap{}
+
+// RegistTemplateFunc 注册模板函数
+func RegistTemplateFunc(name string, fn interface{}) {
+	TemplateFuncs[name] = fn
+}
+
 func (context *Context) Render(templatePath string, data interface{}) interface{} {
 	templateFile := config.ReadFile("templates/" + templatePath)
-	tmpl, err := template.New("test").Parse(string(*templateFile))
+	tmpl, err := template.New("test").Funcs(TemplateFuncs).Parse(string(*templateFile))
 	if err != nil {
 		panic("create template failed, err: " + err.Error())
 	}
@@ -254,6 +294,68 @@ func (context *Context) Render(templatePath string, data interface{}) interface{
 	return &resp
 }
 
+/************************************/
+/*********** FLOW CONTROL ***********/
+/************************************/
+
+// Abort 中断请求，后续中间件及处理函数不再执行，result作为响应结果返回
+//     if c.Session.GetAttribute("user") == nil {
+//         c.Abort(c.Error("未登录"))
+//         return
+//     }
+func (c *Context) Abort(result interface{}) {
+	c.aborted = true
+	c.result = result
+}
+
+// IsAborted 请求是否已被中断
+func (c *Context) IsAborted() bool {
+	return c.aborted
+}
+
+// Result 返回当前的响应结果
+func (c *Context) Result() interface{} {
+	return c.result
+}
+
+// SetResult 设置响应结果，中间件可在next返回后替换处理函数的结果
+func (c *Context) SetResult(result interface{}) {
+	c.result = result
+}
+
+/************************************/
+/************ RESPONSE **************/
+/************************************/
+
+// Status 设置响应HTTP状态码，在渲染响应时写入
+func (c *Context) Status(code int) {
+	c.status = code
+}
+
+// StatusCode 返回 Status 设置的HTTP状态码，未设置时返回0
+func (c *Context) StatusCode() int {
+	return c.status
+}
+
+// Header 设置响应头，value为空时删除该响应头
+func (c *Context) Header(key, value string) {
+	if value == "" {
+		c.HttpResponse.ResponseWriter().Header().Del(key)
+		return
+	}
+	c.HttpResponse.ResponseWriter().Header().Set(key, value)
+}
+
+// AddHeader 追加响应头
+func (c *Context) AddHeader(key, value string) {
+	c.HttpResponse.ResponseWriter().Header().Add(key, value)
+}
+
+// SetCookie 设置响应Cookie
+func (c *Context) SetCookie(cookie *http.Cookie) {
+	http.SetCookie(c.HttpResponse.ResponseWriter(), cookie)
+}
+
 /************************************/
 /************ INPUT DATA ************/
 /************************************/
@@ -390,12 +492,16 @@ func (c *Context) getFormCache() {
 	if c.formCache == nil {
 		c.formCache = make(url.Values)
 		req := c.HttpRequest
-		if err := req.ParseMultipartForm(MaxMultipartMemory); err != nil {
-			if err != http.ErrNotMultipart {
+		if c.ContentType() == MIMEMultipartPOSTForm {
+			if _, err := c.MultipartForm(); err != nil {
 				logger.Error("error on parse multipart form array: %v", err)
 			}
+		} else if err := req.ParseForm(); err != nil {
+			logger.Error("error on parse form array: %v", err)
+		}
+		if req.PostForm != nil {
+			c.formCache = req.PostForm
 		}
-		c.formCache = req.PostForm
 	}
 }
 
@@ -437,96 +543,59 @@ func (c *Context) get(m map[string][]string, key string) (map[string]string, boo
 	return dicts, exist
 }
 
-// // FormFile returns the first file for the provided form key.
-// func (c *Context) FormFile(name string) (*multipart.FileHeader, error) {
-// 	if c.Request.MultipartForm == nil {
-// 		if err := c.Request.ParseMultipartForm(c.engine.MaxMultipartMemory); err != nil {
-// 			return nil, err
-// 		}
-// 	}
-// 	f, fh, err := c.Request.FormFile(name)
-// 	if err != nil {
-// 		return nil, err
-// 	}
-// 	f.Close()
-// 	return fh, err
-// }
-
-// // MultipartForm is the parsed multipart form, including file uploads.
-// func (c *Context) MultipartForm() (*multipart.Form, error) {
-// 	err := c.Request.ParseMultipartForm(c.engine.MaxMultipartMemory)
-// 	return c.Request.MultipartForm, err
-// }
-
-// // SaveUploadedFile uploads the form file to specific dst.
-// func (c *Context) SaveUploadedFile(file *multipart.FileHeader, dst string) error {
-// 	src, err := file.Open()
-// 	if err != nil {
-// 		return err
-// 	}
-// 	defer src.Close()
-
-// 	out, err := os.Create(dst)
-// 	if err != nil {
-// 		return err
-// 	}
-// 	defer out.Close()
-
-// 	_, err = io.Copy(out, src)
-// 	return err
-// }
-
-// // Bind checks the Content-Type to select a binding engine automatically,
-// // Depending the "Content-Type" header different bindings are used:
-// //     "application/json" --> JSON binding
-// //     "application/xml"  --> XML binding
-// // otherwise --> returns an error.
-// // It parses the request's body as JSON if Content-Type == "application/json" using JSON or XML as a JSON input.
-// // It decodes the json payload into the struct specified as a pointer.
-// // It writes a 400 error and sets Content-Type header "text/plain" in the response if input is not valid.
-// func (c *Context) Bind(obj interface{}) error {
-// 	b := binding.Default(c.Request.Method, c.ContentType())
-// 	return c.MustBindWith(obj, b)
-// }
+// Bind checks the Content-Type to select a binding engine automatically,
+// Depending the "Content-Type" header different bindings are used:
+//     "application/json" --> JSON binding
+//     "application/xml"  --> XML binding
+//     "application/x-yaml" --> YAML binding
+//     "application/x-www-form-urlencoded", "multipart/form-data" --> Form binding
+// otherwise --> returns an error.
+// It decodes the payload into the struct specified as a pointer and validates it.
+// It panics if input is not valid.
+func (c *Context) Bind(obj interface{}) error {
+	if err := c.ShouldBind(obj); err != nil {
+		panic(err)
+	}
+	return nil
+}
 
 // BindJSON is a shortcut for c.MustBindWith(obj, binding.JSON).
 func (c *Context) BindJSON(obj interface{}) error {
 	return c.MustBindWith(obj, JSON)
 }
 
-// // BindXML is a shortcut for c.MustBindWith(obj, binding.BindXML).
-// func (c *Context) BindXML(obj interface{}) error {
-// 	return c.MustBindWith(obj, binding.XML)
-// }
+// BindXML is a shortcut for c.MustBindWith(obj, XML).
+func (c *Context) BindXML(obj interface{}) error {
+	return c.MustBindWith(obj, XML)
+}
 
-// // BindQuery is a shortcut for c.MustBindWith(obj, binding.Query).
-// func (c *Context) BindQuery(obj interface{}) error {
-// 	return c.MustBindWith(obj, binding.Query)
-// }
+// BindQuery is a shortcut for c.MustBindWith(obj, Query).
+func (c *Context) BindQuery(obj interface{}) error {
+	return c.MustBindWith(obj, Query)
+}
 
-// // BindYAML is a shortcut for c.MustBindWith(obj, binding.YAML).
-// func (c *Context) BindYAML(obj interface{}) error {
-// 	return c.MustBindWith(obj, binding.YAML)
-// }
+// BindYAML is a shortcut for c.MustBindWith(obj, YAML).
+func (c *Context) BindYAML(obj interface{}) error {
+	return c.MustBindWith(obj, YAML)
+}
 
-// // BindHeader is a shortcut for c.MustBindWith(obj, binding.Header).
-// func (c *Context) BindHeader(obj interface{}) error {
-// 	return c.MustBindWith(obj, binding.Header)
-// }
+// BindHeader is a shortcut for c.MustBindWith(obj, Header).
+func (c *Context) BindHeader(obj interface{}) error {
+	return c.MustBindWith(obj, Header)
+}
 
-// // BindUri binds the passed struct pointer using binding.Uri.
-// // It will abort the request with HTTP 400 if any error occurs.
-// func (c *Context) BindUri(obj interface{}) error {
-// 	if err := c.ShouldBindUri(obj); err != nil {
-// 		c.AbortWithError(http.StatusBadRequest, err).SetType(ErrorTypeBind) // nolint: errcheck
-// 		return err
-// 	}
-// 	return nil
-// }
+// BindUri binds the passed struct pointer using Uri.
+// It panics if any error occurs.
+func (c *Context) BindUri(obj interface{}) error {
+	if err := c.ShouldBindUri(obj); err != nil {
+		panic(err)
+	}
+	return nil
+}
 
 // MustBindWith binds the passed struct pointer using the specified binding engine.
-// It will abort the request with HTTP 400 if any error occurs.
-// See the binding package.
+// It panics with *ValidationError or *BindingError if any error occurs,
+// the router responds with HTTP 400.
 func (c *Context) MustBindWith(obj interface{}, b Binding) error {
 	if err := c.ShouldBindWith(obj, b); err != nil {
 		panic(err)
@@ -534,80 +603,165 @@ func (c *Context) MustBindWith(obj interface{}, b Binding) error {
 	return nil
 }
 
-// // ShouldBind checks the Content-Type to select a binding engine automatically,
-// // Depending the "Content-Type" header different bindings are used:
-// //     "application/json" --> JSON binding
-// //     "application/xml"  --> XML binding
-// // otherwise --> returns an error
-// // It parses the request's body as JSON if Content-Type == "application/json" using JSON or XML as a JSON input.
-// // It decodes the json payload into the struct specified as a pointer.
-// // Like c.Bind() but this method does not set the response status code to 400 and abort if the json is not valid.
-// func (c *Context) ShouldBind(obj interface{}) error {
-// 	b := binding.Default(c.Request.Method, c.ContentType())
-// 	return c.ShouldBindWith(obj, b)
-// }
+// ShouldBind checks the Content-Type to select a binding engine automatically,
+// see Bind and DefaultBinding.
+// Like c.Bind() but this method returns the error instead of panic.
+func (c *Context) ShouldBind(obj interface{}) error {
+	b := DefaultBinding(c.HttpRequest.Method, c.ContentType())
+	if b == nil {
+		return &BindingError{Err: fmt.Errorf("%w %s", ErrUnsupportedContentType, c.ContentType())}
+	}
+	return c.ShouldBindWith(obj, b)
+}
 
-// // ShouldBindJSON is a shortcut for c.ShouldBindWith(obj, binding.JSON).
-// func (c *Context) ShouldBindJSON(obj interface{}) error {
-// 	return c.ShouldBindWith(obj, binding.JSON)
-// }
+// ShouldBindJSON is a shortcut for c.ShouldBindWith(obj, JSON).
+func (c *Context) ShouldBindJSON(obj interface{}) error {
+	return c.ShouldBindWith(obj, JSON)
+}
 
-// // ShouldBindXML is a shortcut for c.ShouldBindWith(obj, binding.XML).
-// func (c *Context) ShouldBindXML(obj interface{}) error {
-// 	return c.ShouldBindWith(obj, binding.XML)
-// }
+// ShouldBindXML is a shortcut for c.ShouldBindWith(obj, XML).
+func (c *Context) ShouldBindXML(obj interface{}) error {
+	return c.ShouldBindWith(obj, XML)
+}
 
-// // ShouldBindQuery is a shortcut for c.ShouldBindWith(obj, binding.Query).
-// func (c *Context) ShouldBindQuery(obj interface{}) error {
-// 	return c.ShouldBindWith(obj, binding.Query)
-// }
+// ShouldBindQuery is a shortcut for c.ShouldBindWith(obj, Query).
+func (c *Context) ShouldBindQuery(obj interface{}) error {
+	return c.ShouldBindWith(obj, Query)
+}
 
-// // ShouldBindYAML is a shortcut for c.ShouldBindWith(obj, binding.YAML).
-// func (c *Context) ShouldBindYAML(obj interface{}) error {
-// 	return c.ShouldBindWith(obj, binding.YAML)
-// }
+// ShouldBindYAML is a shortcut for c.ShouldBindWith(obj, YAML).
+func (c *Context) ShouldBindYAML(obj interface{}) error {
+	return c.ShouldBindWith(obj, YAML)
+}
 
-// // ShouldBindHeader is a shortcut for c.ShouldBindWith(obj, binding.Header).
-// func (c *Context) ShouldBindHeader(obj interface{}) error {
-// 	return c.ShouldBindWith(obj, binding.Header)
-// }
+// ShouldBindHeader is a shortcut for c.ShouldBindWith(obj, Header).
+func (c *Context) ShouldBindHeader(obj interface{}) error {
+	return c.ShouldBindWith(obj, Header)
+}
 
-// // ShouldBindUri binds the passed struct pointer using the specified binding engine.
-// func (c *Context) ShouldBindUri(obj interface{}) error {
-// 	m := make(map[string][]string)
-// 	for _, v := range c.Params {
-// 		m[v.Key] = []string{v.Value}
-// 	}
-// 	return binding.Uri.BindUri(m, obj)
-// }
+// ShouldBindUri binds the passed struct pointer using the specified binding engine.
+func (c *Context) ShouldBindUri(obj interface{}) error {
+	m := make(map[string][]string, len(c.Params))
+	for _, v := range c.Params {
+		m[v.Key] = []string{v.Value}
+	}
+	return c.bindingError(Uri.Name(), Uri.BindUri(m, obj))
+}
+
+// ShouldBindRequest 有请求体时先按Content-Type绑定请求体，再依次绑定查询参数(form)、请求头(header)、路径参数(uri)，最后统一校验
+// 路径参数、请求头及查询参数只绑定设置了对应tag的字段，不使用字段名，且覆盖请求体中的同名字段，
+// 如 `uri:"id" json:"-"` 的字段只能由路径参数设置
+func (c *Context) ShouldBindRequest(obj interface{}) error {
+	req := c.HttpRequest
+	if req.Body != nil && req.Body != http.NoBody && req.ContentLength != 0 {
+		b := DefaultBinding(req.Method, c.ContentType())
+		if b == nil {
+			return &BindingError{Err: fmt.Errorf("%w %s", ErrUnsupportedContentType, c.ContentType())}
+		}
+		// 查询参数按tag绑定，表单只绑定请求体，避免查询参数按字段名设置其他字段
+		if b == Form {
+			b = FormPost
+		}
+		// 校验在绑定全部参数后进行
+		if err := c.ShouldBindWith(obj, b); err != nil {
+			if _, ok := err.(*ValidationError); !ok {
+				return err
+			}
+		}
+	}
+	if err := mapping(obj, "form", valuesLookup(req.URL.Query()), nil, true); err != nil {
+		return c.bindingError(Query.Name(), err)
+	}
+	if err := mapping(obj, "header", headerLookup(req.Header), nil, true); err != nil {
+		return c.bindingError(Header.Name(), err)
+	}
+	m := make(map[string][]string, len(c.Params))
+	for _, v := range c.Params {
+		m[v.Key] = []string{v.Value}
+	}
+	if err := mapping(obj, "uri", valuesLookup(m), nil, true); err != nil {
+		return c.bindingError(Uri.Name(), err)
+	}
+	return c.bindingError("", validate(obj))
+}
 
 // ShouldBindWith binds the passed struct pointer using the specified binding engine.
-// See the binding package.
+// 校验失败返回 *ValidationError，其他错误返回 *BindingError，
+// 在处理函数中返回或经 MustBindWith panic 时响应400及各字段的提示信息
 func (c *Context) ShouldBindWith(obj interface{}, b Binding) error {
-	return b.Bind(c.HttpRequest, obj)
-}
-
-// // ShouldBindBodyWith is similar with ShouldBindWith, but it stores the request
-// // body into the context, and reuse when it is called again.
-// //
-// // NOTE: This method reads the body before binding. So you should use
-// // ShouldBindWith for better performance if you need to call only once.
-// func (c *Context) ShouldBindBodyWith(obj interface{}, bb binding.BindingBody) (err error) {
-// 	var body []byte
-// 	if cb, ok := c.Get(BodyBytesKey); ok {
-// 		if cbb, ok := cb.([]byte); ok {
-// 			body = cbb
-// 		}
-// 	}
-// 	if body == nil {
-// 		body, err = ioutil.ReadAll(c.Request.Body)
-// 		if err != nil {
-// 			return err
-// 		}
-// 		c.Set(BodyBytesKey, body)
-// 	}
-// 	return bb.BindBody(body, obj)
-// }
+	// multipart表单按当前请求的上传限制解析
+	switch b.(type) {
+	case formBinding, formMultipartBinding:
+		if c.ContentType() == MIMEMultipartPOSTForm {
+			if _, err := c.MultipartForm(); err != nil {
+				return err
+			}
+		}
+	}
+	// 请求体已缓存时直接使用缓存
+	if bb, ok := b.(BindingBody); ok && c.body != nil {
+		return c.bindingError(b.Name(), bb.BindBody(c.body, obj))
+	}
+	return c.bindingError(b.Name(), b.Bind(c.HttpRequest, obj))
+}
+
+// ShouldBindBodyWith is similar with ShouldBindWith, but it stores the request
+// body into the context, and reuse when it is called again.
+// 请求体超过 MaxBodyCacheSize 时返回 *BindingError，状态码413
+//
+// NOTE: This method reads the body before binding. So you should use
+// ShouldBindWith for better performance if you need to call only once.
+func (c *Context) ShouldBindBodyWith(obj interface{}, bb BindingBody) error {
+	body, err := c.Body()
+	if err != nil {
+		return c.bindingError(bb.Name(), err)
+	}
+	return c.bindingError(bb.Name(), bb.BindBody(body, obj))
+}
+
+// ErrBodyTooLarge 请求体超过 MaxBodyCacheSize
+var ErrBodyTooLarge = errors.New("request body too large")
+
+// MaxBodyCacheSize Body 可缓存的最大请求体字节数，由 server.max-body-cache-size 配置
+var MaxBodyCacheSize int64 = 10 << 20
+
+// Body 读取并缓存请求体，同一请求中可多次调用，供签名校验、审计日志等中间件及多个 BindingBody 使用
+// 每次调用后 HttpRequest.Body 重置为从头读取缓存内容，之后的 Bind、PostForm 等不受影响
+//     body, err := c.Body()
+//     if err != nil || !verifySign(body, c.HttpRequest.Header.Get("X-Sign")) {
+//         c.Abort(c.ErrorWithStatus(http.StatusUnauthorized, "签名错误"))
+//         return
+//     }
+func (c *Context) Body() ([]byte, error) {
+	if c.body == nil {
+		req := c.HttpRequest
+		if req.Body == nil || req.Body == http.NoBody {
+			c.body = []byte{}
+			return c.body, nil
+		}
+		if req.ContentLength > MaxBodyCacheSize {
+			return nil, ErrBodyTooLarge
+		}
+		body, err := ioutil.ReadAll(io.LimitReader(req.Body, MaxBodyCacheSize+1))
+		if err != nil {
+			return nil, err
+		}
+		if int64(len(body)) > MaxBodyCacheSize {
+			// 超出部分未读取，还原请求体供流式读取
+			req.Body = &multiReadCloser{Reader: io.MultiReader(bytes.NewReader(body), req.Body), Closer: req.Body}
+			return nil, ErrBodyTooLarge
+		}
+		req.Body.Close()
+		c.body = body
+	}
+	c.HttpRequest.Body = ioutil.NopCloser(bytes.NewReader(c.body))
+	return c.body, nil
+}
+
+type multiReadCloser struct {
+	io.Reader
+	io.Closer
+}
 
 // ClientIP 获取客户端IP
 func (c *Context) ClientIP() string {
@@ -624,24 +778,33 @@ func (c *Context) ClientIP() string {
 	return clientIP
 }
 
-// // ContentType returns the Content-Type header of the request.
-// func (c *Context) ContentType() string {
-// 	return filterFlags(c.requestHeader("Content-Type"))
-// }
+// ContentType returns the Content-Type header of the request.
+func (c *Context) ContentType() string {
+	return filterFlags(c.requestHeader("Content-Type"))
+}
 
-// // IsWebsocket returns true if the request headers indicate that a websocket
-// // handshake is being initiated by the client.
-// func (c *Context) IsWebsocket() bool {
-// 	if strings.Contains(strings.ToLower(c.requestHeader("Connection")), "upgrade") &&
-// 		strings.EqualFold(c.requestHeader("Upgrade"), "websocket") {
-// 		return true
-// 	}
-// 	return false
-// }
+// IsWebsocket returns true if the request headers indicate that a websocket
+// handshake is being initiated by the client.
+func (c *Context) IsWebsocket() bool {
+	if strings.Contains(strings.ToLower(c.requestHeader("Connection")), "upgrade") &&
+		strings.EqualFold(c.requestHeader("Upgrade"), "websocket") {
+		return true
+	}
+	return false
+}
 
-// func (c *Context) requestHeader(key string) string {
-// 	return c.Request.Header.Get(key)
-// }
+func (c *Context) requestHeader(key string) string {
+	return c.HttpRequest.Header.Get(key)
+}
+
+func filterFlags(content string) string {
+	for i, char := range content {
+		if char == ' ' || char == ';' {
+			return content[:i]
+		}
+	}
+	return content
+}
 
 type Initializer struct {
 	F func()
@@ -0,0 +1,107 @@
+package context
+
+import (
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// FileResponse 文件响应，FS为nil时按 外部路径 -> ./resources/ -> 可执行文件资源 的顺序查找，见 config.OpenFile
+type FileResponse struct {
+	Name string
+	FS   http.FileSystem
+	// Filename 不为空时以附件形式下载，浏览器保存为该文件名
+	Filename string
+}
+
+// ReaderResponse 读取器响应，Reader实现 io.Seeker 时支持Range请求，实现 io.Closer 时响应后关闭
+type ReaderResponse struct {
+	Reader        io.Reader
+	ContentLength int64 // 小于0时不设置Content-Length
+	ContentType   string
+	Headers       map[string]string
+}
+
+// StreamResponse 流式响应，Step返回false或客户端断开时结束
+type StreamResponse struct {
+	Step func(w io.Writer) bool
+}
+
+// StatusResponse 仅写入状态码及响应头，无响应体
+type StatusResponse struct {
+	Status int
+}
+
+// NoContent 响应204，无响应体
+//
+//	c.Abort(c.NoContent())
+func (c *Context) NoContent() interface{} {
+	return &StatusResponse{Status: http.StatusNoContent}
+}
+
+// File 响应文件，支持 Range、If-Modified-Since 请求，文件不存在时返回404
+//
+//	return c.File("reports/2021.pdf")
+func (c *Context) File(filepath string) interface{} {
+	return &FileResponse{Name: filepath}
+}
+
+// FileFromFS 响应文件系统中的文件，可使用 config.ResourceFileSystem 读取资源目录及可执行文件中的资源
+//
+//	return c.FileFromFS("logo.png", config.ResourceFileSystem("static"))
+func (c *Context) FileFromFS(name string, fs http.FileSystem) interface{} {
+	return &FileResponse{Name: name, FS: fs}
+}
+
+// Attachment 以附件形式下载文件，filename为浏览器保存的文件名，支持中文
+//
+//	return c.Attachment("exports/"+id+".xlsx", "订单导出.xlsx")
+func (c *Context) Attachment(filepath, filename string) interface{} {
+	return &FileResponse{Name: filepath, Filename: filename}
+}
+
+// DataFromReader 响应读取器中的数据，extraHeaders为额外的响应头
+//
+//	return c.DataFromReader(obj.Size, "application/pdf", obj.Body, map[string]string{
+//		"Content-Disposition": context.ContentDisposition("attachment", "合同.pdf"),
+//	})
+func (c *Context) DataFromReader(contentLength int64, contentType string, reader io.Reader, extraHeaders map[string]string) interface{} {
+	return &ReaderResponse{
+		Reader:        reader,
+		ContentLength: contentLength,
+		ContentType:   contentType,
+		Headers:       extraHeaders,
+	}
+}
+
+// Stream 分块流式响应，每次调用step后刷新缓冲区，step返回false或客户端断开时结束，
+// step panic时与处理函数panic相同，由路由的panic处理函数处理
+//
+//	return c.Stream(func(w io.Writer) bool {
+//		row, ok := <-rows
+//		if ok {
+//			fmt.Fprintln(w, row)
+//		}
+//		return ok
+//	})
+func (c *Context) Stream(step func(w io.Writer) bool) interface{} {
+	return &StreamResponse{Step: step}
+}
+
+// ContentDisposition 生成Content-Disposition响应头，非ASCII文件名按RFC 6266使用filename*编码
+func ContentDisposition(disposition, filename string) string {
+	if isASCII(filename) {
+		return disposition + `; filename="` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(filename) + `"`
+	}
+	return disposition + `; filename*=UTF-8''` + strings.ReplaceAll(url.QueryEscape(filename), "+", "%20")
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= 0x80 || s[i] < 0x20 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,73 @@
+package context
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// TrustedProxies 可信的反向代理，由 server.trusted-proxies 配置，为空时 RealIP 不使用 X-Forwarded-For
+var TrustedProxies []*net.IPNet
+
+// ParseTrustedProxies 解析IP或CIDR形式的可信代理，如 127.0.0.1、10.0.0.0/8
+func ParseTrustedProxies(proxies []string) ([]*net.IPNet, error) {
+	nets := make([]*net.IPNet, 0, len(proxies))
+	for _, proxy := range proxies {
+		if !strings.Contains(proxy, "/") {
+			ip := net.ParseIP(proxy)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid trusted proxy '%s'", proxy)
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, ipNet, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy '%s': %v", proxy, err)
+		}
+		nets = append(nets, ipNet)
+	}
+	return nets, nil
+}
+
+// RealIP 客户端无法伪造的IP，用于限流等场景；ClientIP 直接使用请求头，仅适用于日志等场景
+// 请求来自 TrustedProxies 时从 X-Forwarded-For 右侧开始跳过可信代理，取第一个不可信的IP，否则为RemoteAddr中的IP
+func (c *Context) RealIP() string {
+	remoteAddr := strings.TrimSpace(c.HttpRequest.RemoteAddr)
+	ip, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		ip = remoteAddr
+	}
+	if !isTrustedProxy(ip) {
+		return ip
+	}
+	hops := strings.Split(strings.Join(c.HttpRequest.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(hops[i])
+		if net.ParseIP(hop) == nil {
+			break
+		}
+		ip = hop
+		if !isTrustedProxy(hop) {
+			break
+		}
+	}
+	return ip
+}
+
+func isTrustedProxy(ip string) bool {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+	for _, proxy := range TrustedProxies {
+		if proxy.Contains(parsed) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,208 @@
+package context
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"wataru.com/gogo/json"
+)
+
+// ErrStreamClosed 客户端已断开
+var ErrStreamClosed = errors.New("event stream closed")
+
+// SSEvent 服务器推送事件，Data为string或[]byte时原样发送，其他类型序列化为JSON
+type SSEvent struct {
+	ID    string
+	Event string
+	Data  interface{}
+	// Retry 客户端断线重连的等待时间，为0时不发送
+	Retry time.Duration
+}
+
+// SSEResponse 服务器推送事件响应，中间件执行完毕后建立事件流并调用Handler
+type SSEResponse struct {
+	Handler func(stream *EventStream) error
+}
+
+// SSE 服务器推送事件(text/event-stream)，handler返回或客户端断开时结束
+// 路由中间件在事件流建立前执行完毕，会话Cookie等响应头随事件流一起发送
+//
+//	return c.SSE(func(stream *context.EventStream) error {
+//		for {
+//			select {
+//			case p := <-progress:
+//				if err := stream.Send(context.SSEvent{ID: p.ID, Event: "progress", Data: p}); err != nil {
+//					return err
+//				}
+//			case <-stream.Done():
+//				return nil
+//			}
+//		}
+//	})
+func (c *Context) SSE(handler func(stream *EventStream) error) interface{} {
+	return &SSEResponse{Handler: handler}
+}
+
+// EventStream 事件流，每个事件写入后立即刷新，可在多个goroutine中使用
+type EventStream struct {
+	mu          sync.Mutex
+	w           io.Writer
+	flusher     http.Flusher
+	done        <-chan struct{}
+	lastEventID string
+	// stop Close 后关闭，停止 KeepAlive
+	stop   chan struct{}
+	closed bool
+}
+
+// NewEventStream 写入事件流响应头并创建事件流，由渲染器在中间件执行完毕后调用
+func NewEventStream(c *Context) *EventStream {
+	resp := c.HttpResponse.ResponseWriter()
+	header := resp.Header()
+	header.Set("Content-Type", "text/event-stream")
+	header.Set("Cache-Control", "no-cache")
+	header.Set("Connection", "keep-alive")
+	// 禁用nginx代理缓冲
+	header.Set("X-Accel-Buffering", "no")
+	header.Del("Content-Length")
+	status := c.StatusCode()
+	if status == 0 {
+		status = http.StatusOK
+	}
+	resp.WriteHeader(status)
+	flusher, _ := resp.(http.Flusher)
+	if flusher != nil {
+		flusher.Flush()
+	}
+	lastEventID := c.HttpRequest.Header.Get("Last-Event-ID")
+	if lastEventID == "" {
+		// EventSource polyfill 无法设置请求头时通过查询参数传递
+		lastEventID = c.Query("lastEventId")
+	}
+	return &EventStream{
+		w:           resp,
+		flusher:     flusher,
+		done:        c.HttpRequest.Context().Done(),
+		lastEventID: lastEventID,
+		stop:        make(chan struct{}),
+	}
+}
+
+// Close 结束事件流，之后的写入返回 ErrStreamClosed，由渲染器在Handler返回后调用
+func (s *EventStream) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.closed {
+		s.closed = true
+		close(s.stop)
+	}
+}
+
+// LastEventID 客户端断线重连时携带的最后一个事件ID，用于从断点继续推送
+func (s *EventStream) LastEventID() string {
+	return s.lastEventID
+}
+
+// Done 客户端断开时关闭
+func (s *EventStream) Done() <-chan struct{} {
+	return s.done
+}
+
+// Closed 客户端是否已断开
+func (s *EventStream) Closed() bool {
+	select {
+	case <-s.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// Send 发送事件
+func (s *EventStream) Send(event SSEvent) error {
+	var sb strings.Builder
+	if event.ID != "" {
+		sb.WriteString("id: " + singleLine(event.ID) + "\n")
+	}
+	if event.Event != "" {
+		sb.WriteString("event: " + singleLine(event.Event) + "\n")
+	}
+	if event.Retry > 0 {
+		sb.WriteString(fmt.Sprintf("retry: %d\n", event.Retry.Milliseconds()))
+	}
+	if event.Data != nil {
+		var data string
+		switch v := event.Data.(type) {
+		case string:
+			data = v
+		case []byte:
+			data = string(v)
+		default:
+			data = json.ToJson(v)
+		}
+		for _, line := range strings.Split(strings.ReplaceAll(data, "\r\n", "\n"), "\n") {
+			sb.WriteString("data: " + line + "\n")
+		}
+	}
+	sb.WriteString("\n")
+	return s.write(sb.String())
+}
+
+// Event 发送指定事件名的事件
+func (s *EventStream) Event(event string, data interface{}) error {
+	return s.Send(SSEvent{Event: event, Data: data})
+}
+
+// Retry 设置客户端断线重连的等待时间
+func (s *EventStream) Retry(d time.Duration) error {
+	return s.write(fmt.Sprintf("retry: %d\n\n", d.Milliseconds()))
+}
+
+// Comment 发送注释，客户端忽略，可用于保持连接
+func (s *EventStream) Comment(text string) error {
+	return s.write(": " + singleLine(text) + "\n\n")
+}
+
+// KeepAlive 每隔interval发送一次注释以防止代理超时断开，客户端断开时停止
+func (s *EventStream) KeepAlive(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if s.Comment("ping") != nil {
+					return
+				}
+			case <-s.done:
+				return
+			case <-s.stop:
+				return
+			}
+		}
+	}()
+}
+
+func (s *EventStream) write(data string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed || s.Closed() {
+		return ErrStreamClosed
+	}
+	if _, err := io.WriteString(s.w, data); err != nil {
+		return err
+	}
+	if s.flusher != nil {
+		s.flusher.Flush()
+	}
+	return nil
+}
+
+func singleLine(s string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(s)
+}
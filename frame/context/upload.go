@@ -0,0 +1,260 @@
+package context
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	// ErrTooManyFiles 上传文件数量超过 UploadLimit.MaxFiles
+	ErrTooManyFiles = errors.New("too many files")
+	// ErrFileTypeNotAllowed 上传文件类型不在 UploadLimit.AllowedTypes 中
+	ErrFileTypeNotAllowed = errors.New("file type not allowed")
+)
+
+// UploadLimit 文件上传限制，字段为0或空时不限制
+type UploadLimit struct {
+	// MaxSize 请求体最大字节数，超出时返回413
+	MaxSize int64
+	// MaxMemory 解析时保存在内存中的最大字节数，超出的文件写入临时文件，请求结束后删除，为0时使用 MaxMultipartMemory
+	MaxMemory int64
+	// MaxFiles 文件数量上限
+	MaxFiles int
+	// AllowedTypes 允许的文件MIME类型，按 http.DetectContentType 识别的文件内容校验，不使用客户端声明的Content-Type，支持 image/* 形式的通配
+	AllowedTypes []string
+}
+
+// DefaultUploadLimit 未通过 SetUploadLimit 设置时使用的上传限制，由 server.upload 配置
+var DefaultUploadLimit = UploadLimit{}
+
+// SetUploadLimit 设置当前请求的上传限制，需在解析表单前调用，通常由路由中间件 UploadMiddleware 设置
+func (c *Context) SetUploadLimit(limit UploadLimit) {
+	c.uploadLimit = &limit
+}
+
+func (c *Context) getUploadLimit() UploadLimit {
+	if c.uploadLimit != nil {
+		return *c.uploadLimit
+	}
+	return DefaultUploadLimit
+}
+
+// MultipartForm is the parsed multipart form, including file uploads.
+// 按 UploadLimit 限制请求体大小、文件数量及类型，错误为 *BindingError
+func (c *Context) MultipartForm() (*multipart.Form, error) {
+	if c.multipartErr == nil && c.HttpRequest.MultipartForm == nil {
+		c.multipartErr = c.parseMultipartForm()
+	}
+	if c.multipartErr != nil {
+		return nil, c.multipartErr
+	}
+	return c.HttpRequest.MultipartForm, nil
+}
+
+func (c *Context) parseMultipartForm() error {
+	req := c.HttpRequest
+	limit := c.getUploadLimit()
+	var body *limitedBody
+	if limit.MaxSize > 0 {
+		if req.ContentLength > limit.MaxSize {
+			return &BindingError{Binding: FormMultipart.Name(), Err: ErrBodyTooLarge}
+		}
+		body = &limitedBody{ReadCloser: req.Body, remaining: limit.MaxSize}
+		req.Body = body
+	}
+	// 限制文件数量或类型时边读取边校验，超出限制的文件不写入临时文件
+	var files *uploadReader
+	if limit.MaxFiles > 0 || len(limit.AllowedTypes) > 0 {
+		if files = newUploadReader(req.Body, req.Header.Get("Content-Type"), limit); files != nil {
+			req.Body = files
+			req.Header.Set("Content-Type", files.mw.FormDataContentType())
+		}
+	}
+	maxMemory := limit.MaxMemory
+	if maxMemory <= 0 {
+		maxMemory = MaxMultipartMemory
+	}
+	if err := req.ParseMultipartForm(maxMemory); err != nil {
+		if body != nil && body.exceeded {
+			err = ErrBodyTooLarge
+		} else if files != nil && files.limitErr != nil {
+			err = files.limitErr
+		}
+		return &BindingError{Binding: FormMultipart.Name(), Err: err}
+	}
+	return nil
+}
+
+// uploadReader 使用 multipart.Reader 逐个读取请求体中的部分，校验文件数量及文件内容类型后重新编码，
+// 供 ParseMultipartForm 读取；超出限制时在读取到该文件时返回错误，不会写入临时文件
+type uploadReader struct {
+	io.Closer
+	mr       *multipart.Reader
+	mw       *multipart.Writer
+	buf      bytes.Buffer
+	part     *multipart.Part
+	w        io.Writer
+	limit    UploadLimit
+	files    int
+	err      error
+	limitErr error
+}
+
+// newUploadReader Content-Type 中没有boundary时返回nil，由 ParseMultipartForm 返回错误
+func newUploadReader(body io.ReadCloser, contentType string, limit UploadLimit) *uploadReader {
+	_, params, err := mime.ParseMediaType(contentType)
+	if err != nil || params["boundary"] == "" {
+		return nil
+	}
+	r := &uploadReader{
+		Closer: body,
+		mr:     multipart.NewReader(body, params["boundary"]),
+		limit:  limit,
+	}
+	r.mw = multipart.NewWriter(&r.buf)
+	return r
+}
+
+func (r *uploadReader) Read(p []byte) (int, error) {
+	for r.buf.Len() == 0 && r.err == nil {
+		r.err = r.next()
+	}
+	if r.buf.Len() > 0 {
+		return r.buf.Read(p)
+	}
+	return 0, r.err
+}
+
+// next 写入当前部分的下一块数据，当前部分读取完时读取下一个部分，全部读取完时写入结束边界并返回 io.EOF
+func (r *uploadReader) next() error {
+	if r.part != nil {
+		if _, err := io.CopyN(r.w, r.part, 32<<10); err != io.EOF {
+			return err
+		}
+		r.part = nil
+		return nil
+	}
+	part, err := r.mr.NextPart()
+	if err == io.EOF {
+		if err := r.mw.Close(); err != nil {
+			return err
+		}
+		return io.EOF
+	}
+	if err != nil {
+		return err
+	}
+	var head []byte
+	if part.FileName() != "" {
+		if head, err = r.checkFile(part); err != nil {
+			return err
+		}
+	}
+	if r.w, err = r.mw.CreatePart(part.Header); err != nil {
+		return err
+	}
+	if _, err = r.w.Write(head); err != nil {
+		return err
+	}
+	r.part = part
+	return nil
+}
+
+// checkFile 校验文件数量，读取文件开头的512字节识别文件类型，返回已读取的数据
+func (r *uploadReader) checkFile(part *multipart.Part) ([]byte, error) {
+	r.files++
+	if r.limit.MaxFiles > 0 && r.files > r.limit.MaxFiles {
+		r.limitErr = fmt.Errorf("%w: more than %d", ErrTooManyFiles, r.limit.MaxFiles)
+		return nil, r.limitErr
+	}
+	head := make([]byte, 512)
+	n, err := io.ReadFull(part, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return nil, err
+	}
+	head = head[:n]
+	if !fileTypeAllowed(http.DetectContentType(head), r.limit.AllowedTypes) {
+		r.limitErr = fmt.Errorf("%w: %s", ErrFileTypeNotAllowed, part.FileName())
+		return nil, r.limitErr
+	}
+	return head, nil
+}
+
+func fileTypeAllowed(contentType string, allowedTypes []string) bool {
+	if len(allowedTypes) == 0 {
+		return true
+	}
+	fileType, _, _ := mime.ParseMediaType(contentType)
+	for _, allowed := range allowedTypes {
+		if allowed == fileType || (strings.HasSuffix(allowed, "/*") && strings.HasPrefix(fileType, strings.TrimSuffix(allowed, "*"))) {
+			return true
+		}
+	}
+	return false
+}
+
+// FormFile returns the first file for the provided form key.
+func (c *Context) FormFile(name string) (*multipart.FileHeader, error) {
+	form, err := c.MultipartForm()
+	if err != nil {
+		return nil, err
+	}
+	if files := form.File[name]; len(files) > 0 {
+		return files[0], nil
+	}
+	return nil, http.ErrMissingFile
+}
+
+// SaveUploadedFile uploads the form file to specific dst, 目标目录不存在时自动创建
+func (c *Context) SaveUploadedFile(file *multipart.FileHeader, dst string) error {
+	src, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	if err = os.MkdirAll(filepath.Dir(dst), 0750); err != nil {
+		return err
+	}
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	_, err = io.Copy(out, src)
+	return err
+}
+
+// limitedBody 限制请求体读取的字节数，超出时返回 ErrBodyTooLarge
+type limitedBody struct {
+	io.ReadCloser
+	remaining int64
+	exceeded  bool
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if b.remaining <= 0 {
+		// 恰好读完时再尝试读取一个字节判断是否超出
+		var one [1]byte
+		if n, _ := b.ReadCloser.Read(one[:]); n > 0 {
+			b.exceeded = true
+			return 0, ErrBodyTooLarge
+		}
+		return 0, io.EOF
+	}
+	if int64(len(p)) > b.remaining {
+		p = p[:b.remaining]
+	}
+	n, err := b.ReadCloser.Read(p)
+	b.remaining -= int64(n)
+	return n, err
+}
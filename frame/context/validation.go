@@ -0,0 +1,212 @@
+package context
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// BindingError 请求参数解析失败，如请求体格式错误、参数类型不匹配、不支持的Content-Type
+type BindingError struct {
+	Binding string
+	Err     error
+}
+
+func (e *BindingError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *BindingError) Unwrap() error {
+	return e.Err
+}
+
+// StatusCode 不支持的Content-Type或文件类型为415，请求体过大为413，其他为400
+func (e *BindingError) StatusCode() int {
+	switch {
+	case errors.Is(e.Err, ErrUnsupportedContentType), errors.Is(e.Err, ErrFileTypeNotAllowed):
+		return http.StatusUnsupportedMediaType
+	case errors.Is(e.Err, ErrBodyTooLarge):
+		return http.StatusRequestEntityTooLarge
+	}
+	return http.StatusBadRequest
+}
+
+// ValidationError 请求参数校验失败，Fields为字段到提示信息的映射，嵌套字段以"."分隔
+type ValidationError struct {
+	Fields map[string]string
+	Errors validator.ValidationErrors
+	// messages 按校验顺序排列的提示信息
+	messages []string
+}
+
+func (e *ValidationError) Error() string {
+	return strings.Join(e.messages, "; ")
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.Errors
+}
+
+func (e *ValidationError) StatusCode() int {
+	return http.StatusBadRequest
+}
+
+// DefaultValidationLocale 请求的 Accept-Language 没有对应的提示信息时使用的语言
+var DefaultValidationLocale = "zh"
+
+var (
+	validationMessagesMu sync.RWMutex
+	// validationMessages 语言 -> 校验tag -> 提示信息，"tag.len" 用于字符串、切片及map的长度校验
+	validationMessages = map[string]map[string]string{
+		"zh": {
+			"default":  "{field}格式不正确",
+			"required": "{field}不能为空",
+			"len":      "{field}必须等于{param}",
+			"len.len":  "{field}长度必须为{param}",
+			"min":      "{field}不能小于{param}",
+			"min.len":  "{field}长度不能小于{param}",
+			"max":      "{field}不能大于{param}",
+			"max.len":  "{field}长度不能大于{param}",
+			"gt":       "{field}必须大于{param}",
+			"gte":      "{field}不能小于{param}",
+			"lt":       "{field}必须小于{param}",
+			"lte":      "{field}不能大于{param}",
+			"eq":       "{field}必须等于{param}",
+			"ne":       "{field}不能等于{param}",
+			"oneof":    "{field}必须是[{param}]中的一个",
+			"email":    "{field}必须是有效的邮箱地址",
+			"url":      "{field}必须是有效的URL",
+			"uuid":     "{field}必须是有效的UUID",
+			"numeric":  "{field}必须是数字",
+			"alphanum": "{field}只能包含字母和数字",
+			"datetime": "{field}必须符合格式{param}",
+		},
+		"en": {
+			"default":  "{field} is invalid",
+			"required": "{field} is required",
+			"len":      "{field} must be equal to {param}",
+			"len.len":  "{field} must be {param} in length",
+			"min":      "{field} must be {param} or greater",
+			"min.len":  "{field} must be at least {param} in length",
+			"max":      "{field} must be {param} or less",
+			"max.len":  "{field} must be at most {param} in length",
+			"gt":       "{field} must be greater than {param}",
+			"gte":      "{field} must be {param} or greater",
+			"lt":       "{field} must be less than {param}",
+			"lte":      "{field} must be {param} or less",
+			"eq":       "{field} must be equal to {param}",
+			"ne":       "{field} must not be equal to {param}",
+			"oneof":    "{field} must be one of [{param}]",
+			"email":    "{field} must be a valid email address",
+			"url":      "{field} must be a valid URL",
+			"uuid":     "{field} must be a valid UUID",
+			"numeric":  "{field} must be numeric",
+			"alphanum": "{field} can only contain letters and numbers",
+			"datetime": "{field} must match the format {param}",
+		},
+	}
+)
+
+// RegistValidationMessages 注册或覆盖校验提示信息，{field} 替换为字段名，{param} 替换为校验参数
+//
+//	context.RegistValidationMessages("zh", map[string]string{"mobile": "{field}必须是有效的手机号"})
+func RegistValidationMessages(locale string, messages map[string]string) {
+	validationMessagesMu.Lock()
+	defer validationMessagesMu.Unlock()
+	locale = strings.ToLower(locale)
+	if validationMessages[locale] == nil {
+		validationMessages[locale] = make(map[string]string)
+	}
+	for tag, message := range messages {
+		validationMessages[locale][tag] = message
+	}
+}
+
+// ValidationTranslator 将字段校验错误转换为提示信息，可替换为自定义实现
+var ValidationTranslator = func(locale string, fe validator.FieldError) string {
+	validationMessagesMu.RLock()
+	defer validationMessagesMu.RUnlock()
+	keys := []string{fe.Tag()}
+	switch fe.Kind() {
+	case reflect.String, reflect.Slice, reflect.Map, reflect.Array:
+		keys = append([]string{fe.Tag() + ".len"}, keys...)
+	}
+	keys = append(keys, "default")
+	for _, l := range []string{locale, DefaultValidationLocale} {
+		for _, key := range keys {
+			if message, ok := validationMessages[l][key]; ok {
+				return strings.NewReplacer("{field}", fe.Field(), "{param}", fe.Param()).Replace(message)
+			}
+		}
+	}
+	return fe.Error()
+}
+
+// validationLocale 按 Accept-Language 选择有提示信息的语言，如 "en-US,en;q=0.9" 依次尝试 en-us、en
+func validationLocale(acceptLanguage string) string {
+	validationMessagesMu.RLock()
+	defer validationMessagesMu.RUnlock()
+	for _, lang := range strings.Split(acceptLanguage, ",") {
+		lang = strings.ToLower(strings.TrimSpace(strings.Split(lang, ";")[0]))
+		if _, ok := validationMessages[lang]; ok {
+			return lang
+		}
+		if i := strings.IndexByte(lang, '-'); i > 0 {
+			if _, ok := validationMessages[lang[:i]]; ok {
+				return lang[:i]
+			}
+		}
+	}
+	return DefaultValidationLocale
+}
+
+func newValidationError(locale string, errs validator.ValidationErrors) *ValidationError {
+	e := &ValidationError{
+		Fields: make(map[string]string, len(errs)),
+		Errors: errs,
+	}
+	for _, fe := range errs {
+		field := fe.Namespace()
+		// 去掉顶层结构体名称
+		if i := strings.IndexByte(field, '.'); i >= 0 {
+			field = field[i+1:]
+		}
+		if _, ok := e.Fields[field]; ok {
+			continue
+		}
+		message := ValidationTranslator(locale, fe)
+		e.Fields[field] = message
+		e.messages = append(e.messages, message)
+	}
+	return e
+}
+
+// bindingError 将绑定错误转换为 *ValidationError 或 *BindingError
+func (c *Context) bindingError(binding string, err error) error {
+	if err == nil {
+		return nil
+	}
+	var errs validator.ValidationErrors
+	if errors.As(err, &errs) {
+		return newValidationError(validationLocale(c.requestHeader("Accept-Language")), errs)
+	}
+	return &BindingError{Binding: binding, Err: err}
+}
+
+// fieldTagName 校验错误中的字段名依次取 json、form、uri、header 标签，与前端提交的参数名一致
+func fieldTagName(field reflect.StructField) string {
+	for _, tag := range []string{"json", "form", "uri", "header"} {
+		name := strings.Split(field.Tag.Get(tag), ",")[0]
+		if name == "-" {
+			continue
+		}
+		if name != "" {
+			return name
+		}
+	}
+	return ""
+}
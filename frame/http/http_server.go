@@ -77,6 +77,15 @@ func (server *HttpServer) Run() {
 	serverConf := util.ValueOrDefault((*config.GlobalConfig.Map)["server"], make(map[interface{}]interface{})).(map[interface{}]interface{})
 	logger.Info("Run in %s mode", config.GlobalConfig.Env)
 
+	// 输出路由表后退出，不连接数据源及Redis，执行初始化器以包含其中注册的路由
+	// 初始化器中访问数据源时需判断 config.GlobalConfig.PrintRoutes
+	if config.GlobalConfig.PrintRoutes {
+		server.doInitializer()
+		server.router.InitRouterMiddleware()
+		server.router.PrintRoutes(os.Stdout)
+		return
+	}
+
 	// 初始化数据源连接
 	_, dbCancel := db.InitDb()
 	defer dbCancel()
@@ -108,7 +117,7 @@ func (server *HttpServer) Run() {
 	}()
 	logger.Info("Started server [%d] in %.3f seconds", port, float32(time.Now().UnixNano()-startTime)/1e9)
 
-	quit := make(chan os.Signal)
+	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, os.Interrupt)
 	<-quit
 	logger.Info("Shutdown server ...")
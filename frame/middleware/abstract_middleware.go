@@ -1,10 +1,67 @@
 package middleware
 
 import (
+	"reflect"
+	"runtime"
+	"strings"
+
 	"wataru.com/gogo/frame/context"
 )
 
+// Middleware 前后置中间件，Before按注册顺序执行，After按注册顺序的逆序执行
 type Middleware interface {
 	Before(c *context.Context)
 	After(c *context.Context)
 }
+
+// Handler 洋葱模型中间件，调用next进入下一层，不调用next则请求在此中断
+type Handler interface {
+	Handle(c *context.Context, next func())
+}
+
+// HandlerFunc 函数形式的洋葱模型中间件
+type HandlerFunc func(c *context.Context, next func())
+
+// Handle ...
+func (f HandlerFunc) Handle(c *context.Context, next func()) {
+	f(c, next)
+}
+
+// adapter 将Before/After中间件适配为洋葱模型中间件
+type adapter struct {
+	mw Middleware
+}
+
+// Handle Before中调用c.Abort时跳过后续中间件及处理函数，After仍会执行
+func (a adapter) Handle(c *context.Context, next func()) {
+	a.mw.Before(c)
+	if !c.IsAborted() {
+		next()
+	}
+	a.mw.After(c)
+}
+
+// Adapt 将Before/After中间件转换为洋葱模型中间件
+func Adapt(mw Middleware) Handler {
+	return adapter{mw: mw}
+}
+
+// Name 中间件名称，用于路由日志
+func Name(h Handler) string {
+	switch v := h.(type) {
+	case adapter:
+		return typeName(v.mw)
+	case HandlerFunc:
+		name := runtime.FuncForPC(reflect.ValueOf(v).Pointer()).Name()
+		return name[strings.LastIndexByte(name, '/')+1:]
+	}
+	return typeName(h)
+}
+
+func typeName(v interface{}) string {
+	t := reflect.TypeOf(v)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.Name()
+}
@@ -0,0 +1,344 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+	"wataru.com/gogo/config"
+	"wataru.com/gogo/frame/context"
+	"wataru.com/gogo/util"
+)
+
+// CompressConfig 响应压缩配置
+type CompressConfig struct {
+	// Encodings 支持的压缩算法，按优先级排列，可选 br、gzip，为空时使用 br, gzip
+	Encodings []string
+	// GzipLevel gzip压缩级别，1-9，为0时使用默认级别
+	GzipLevel int
+	// BrotliLevel brotli压缩级别，0-11，为0时使用4
+	BrotliLevel int
+	// MinSize 响应体小于该字节数时不压缩
+	MinSize int
+	// ContentTypes 压缩的响应类型，支持 text/* 通配，为空时使用 DefaultCompressContentTypes
+	ContentTypes []string
+}
+
+// DefaultCompressContentTypes 默认压缩的响应类型
+var DefaultCompressContentTypes = []string{
+	"text/html",
+	"text/plain",
+	"text/css",
+	"text/xml",
+	"text/javascript",
+	"application/javascript",
+	"application/json",
+	"application/xml",
+	"application/x-yaml",
+	"image/svg+xml",
+}
+
+// CompressMiddleware 响应压缩中间件，按请求的 Accept-Encoding 使用 brotli 或 gzip 压缩响应体
+// 响应体在达到 min-size 前缓冲，响应结束时仍不足 min-size 的响应原样返回并设置 Content-Length；
+// 流式响应在首次刷新时开始压缩，此后每次刷新都将已压缩的数据发送到客户端
+//
+//	server:
+//	  middlewares: [compress, log, session]
+//	  compression:
+//	    encodings: [br, gzip]
+//	    gzip-level: 6
+//	    brotli-level: 4
+//	    min-size: 1024
+//	    content-types: [application/json, text/*]
+type CompressMiddleware struct {
+	encodings    []string
+	minSize      int
+	contentTypes []string
+	pools        map[string]*sync.Pool
+}
+
+// compressor gzip.Writer 与 brotli.Writer 的公共方法
+type compressor interface {
+	io.WriteCloser
+	Flush() error
+	Reset(w io.Writer)
+}
+
+// Handle ...
+func (middleware CompressMiddleware) Handle(c *context.Context, next func()) {
+	if c.HttpRequest.Method == http.MethodHead || c.IsWebsocket() {
+		next()
+		return
+	}
+	resp := c.HttpResponse.ResponseWriter()
+	resp.Header().Add("Vary", "Accept-Encoding")
+	encoding := negotiateEncoding(c.HttpRequest.Header.Get("Accept-Encoding"), middleware.encodings)
+	if encoding == "" {
+		next()
+		return
+	}
+	c.HttpResponse.Wrap(&compressResponseWriter{
+		ResponseWriter: resp,
+		middleware:     &middleware,
+		encoding:       encoding,
+	})
+	next()
+}
+
+func (middleware *CompressMiddleware) isContentTypeAllowed(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	for _, allowed := range middleware.contentTypes {
+		if allowed == mediaType || (strings.HasSuffix(allowed, "/*") && strings.HasPrefix(mediaType, strings.TrimSuffix(allowed, "*"))) {
+			return true
+		}
+	}
+	return false
+}
+
+// negotiateEncoding 按 Accept-Encoding 中的q值选择压缩算法，q值相同时按encodings的顺序
+func negotiateEncoding(acceptEncoding string, encodings []string) string {
+	if acceptEncoding == "" {
+		return ""
+	}
+	qvalues := make(map[string]float64)
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		params := strings.Split(part, ";")
+		name := strings.ToLower(strings.TrimSpace(params[0]))
+		if name == "" {
+			continue
+		}
+		q := 1.0
+		for _, param := range params[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if v, err := strconv.ParseFloat(param[2:], 64); err == nil {
+					q = v
+				}
+			}
+		}
+		qvalues[name] = q
+	}
+	best, bestQ := "", 0.0
+	for _, encoding := range encodings {
+		q, ok := qvalues[encoding]
+		if !ok {
+			q, ok = qvalues["*"]
+		}
+		if ok && q > bestQ {
+			best, bestQ = encoding, q
+		}
+	}
+	return best
+}
+
+// compressResponseWriter 压缩响应写入器，状态码在确定是否压缩后才写入
+type compressResponseWriter struct {
+	http.ResponseWriter
+	middleware *CompressMiddleware
+	encoding   string
+	// status 已调用 WriteHeader 的状态码
+	status int
+	// decided 已确定是否压缩并写入状态码
+	decided bool
+	// buf 确定是否压缩前缓冲的响应体
+	buf        []byte
+	compressor compressor
+	closed     bool
+}
+
+func (w *compressResponseWriter) WriteHeader(status int) {
+	if w.status != 0 {
+		return
+	}
+	w.status = status
+	header := w.Header()
+	if status < http.StatusOK || status == http.StatusNoContent || status == http.StatusPartialContent ||
+		status == http.StatusNotModified || header.Get("Content-Encoding") != "" || header.Get("Content-Range") != "" {
+		w.decide(false)
+		return
+	}
+	if contentType := header.Get("Content-Type"); contentType != "" && !w.middleware.isContentTypeAllowed(contentType) {
+		w.decide(false)
+		return
+	}
+	if contentLength, err := strconv.Atoi(header.Get("Content-Length")); err == nil {
+		if contentLength < w.middleware.minSize {
+			w.decide(false)
+		} else if header.Get("Content-Type") != "" {
+			w.decide(true)
+		}
+	}
+}
+
+func (w *compressResponseWriter) Write(p []byte) (int, error) {
+	if w.status == 0 {
+		w.WriteHeader(http.StatusOK)
+	}
+	if !w.decided {
+		w.buf = append(w.buf, p...)
+		if len(w.buf) >= w.middleware.minSize {
+			if err := w.start(); err != nil {
+				return 0, err
+			}
+		}
+		return len(p), nil
+	}
+	if w.compressor != nil {
+		return w.compressor.Write(p)
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+// Flush 流式响应刷新时开始压缩，将已压缩的数据发送到客户端
+func (w *compressResponseWriter) Flush() {
+	if w.status == 0 {
+		w.WriteHeader(http.StatusOK)
+	}
+	if !w.decided {
+		if len(w.buf) == 0 && w.Header().Get("Content-Type") == "" {
+			w.decide(false)
+		} else if w.start() != nil {
+			return
+		}
+	}
+	if w.compressor != nil && w.compressor.Flush() != nil {
+		return
+	}
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Close 写入剩余数据，响应体不足 min-size 时原样返回
+func (w *compressResponseWriter) Close() error {
+	if w.closed || w.status == 0 {
+		return nil
+	}
+	w.closed = true
+	if !w.decided {
+		if w.Header().Get("Content-Length") == "" {
+			w.Header().Set("Content-Length", strconv.Itoa(len(w.buf)))
+		}
+		w.decide(false)
+		if _, err := w.ResponseWriter.Write(w.buf); err != nil {
+			return err
+		}
+		w.buf = nil
+	}
+	if w.compressor == nil {
+		return nil
+	}
+	err := w.compressor.Close()
+	w.compressor.Reset(nil)
+	w.middleware.pools[w.encoding].Put(w.compressor)
+	w.compressor = nil
+	return err
+}
+
+// start 根据缓冲的响应体确定响应类型及是否压缩，并写入缓冲的数据
+func (w *compressResponseWriter) start() error {
+	header := w.Header()
+	if header.Get("Content-Type") == "" {
+		header.Set("Content-Type", http.DetectContentType(w.buf))
+	}
+	w.decide(w.middleware.isContentTypeAllowed(header.Get("Content-Type")))
+	buf := w.buf
+	w.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+	var err error
+	if w.compressor != nil {
+		_, err = w.compressor.Write(buf)
+	} else {
+		_, err = w.ResponseWriter.Write(buf)
+	}
+	return err
+}
+
+// decide 确定是否压缩并写入状态码，压缩后的长度未知，删除 Content-Length
+func (w *compressResponseWriter) decide(compress bool) {
+	w.decided = true
+	if compress {
+		header := w.Header()
+		header.Del("Content-Length")
+		header.Del("Accept-Ranges")
+		header.Set("Content-Encoding", w.encoding)
+		w.compressor = w.middleware.pools[w.encoding].Get().(compressor)
+		w.compressor.Reset(w.ResponseWriter)
+	}
+	w.ResponseWriter.WriteHeader(w.status)
+}
+
+// NewCompressMiddleware ...
+func NewCompressMiddleware(compressConfig CompressConfig) CompressMiddleware {
+	middleware := CompressMiddleware{
+		encodings:    []string{"br", "gzip"},
+		minSize:      compressConfig.MinSize,
+		contentTypes: DefaultCompressContentTypes,
+		pools:        make(map[string]*sync.Pool),
+	}
+	if len(compressConfig.Encodings) > 0 {
+		middleware.encodings = make([]string, 0, len(compressConfig.Encodings))
+		for _, encoding := range compressConfig.Encodings {
+			middleware.encodings = append(middleware.encodings, strings.ToLower(encoding))
+		}
+	}
+	if len(compressConfig.ContentTypes) > 0 {
+		middleware.contentTypes = make([]string, 0, len(compressConfig.ContentTypes))
+		for _, contentType := range compressConfig.ContentTypes {
+			middleware.contentTypes = append(middleware.contentTypes, strings.ToLower(contentType))
+		}
+	}
+	gzipLevel := compressConfig.GzipLevel
+	if gzipLevel == 0 {
+		gzipLevel = gzip.DefaultCompression
+	}
+	brotliLevel := compressConfig.BrotliLevel
+	if brotliLevel == 0 {
+		brotliLevel = 4
+	}
+	for _, encoding := range middleware.encodings {
+		switch encoding {
+		case "gzip":
+			if _, err := gzip.NewWriterLevel(nil, gzipLevel); err != nil {
+				panic("Compress middleware: " + err.Error())
+			}
+			middleware.pools[encoding] = &sync.Pool{New: func() interface{} {
+				w, _ := gzip.NewWriterLevel(nil, gzipLevel)
+				return w
+			}}
+		case "br":
+			if brotliLevel < brotli.BestSpeed || brotliLevel > brotli.BestCompression {
+				panic("Compress middleware: invalid brotli level " + strconv.Itoa(brotliLevel))
+			}
+			middleware.pools[encoding] = &sync.Pool{New: func() interface{} {
+				return brotli.NewWriterLevel(nil, brotliLevel)
+			}}
+		default:
+			panic("Compress middleware: unsupported encoding " + encoding)
+		}
+	}
+	return middleware
+}
+
+// NewCompressMiddlewareFromConfig 读取 server.compression 配置创建压缩中间件
+func NewCompressMiddlewareFromConfig() CompressMiddleware {
+	serverConf := util.ValueOrDefault((*config.GlobalConfig.Map)["server"], make(map[interface{}]interface{})).(map[interface{}]interface{})
+	compressConf := util.ValueOrDefault(serverConf["compression"], make(map[interface{}]interface{})).(map[interface{}]interface{})
+	return NewCompressMiddleware(CompressConfig{
+		Encodings:    stringList(compressConf["encodings"]),
+		GzipLevel:    util.ValueOrDefault(compressConf["gzip-level"], 0).(int),
+		BrotliLevel:  util.ValueOrDefault(compressConf["brotli-level"], 0).(int),
+		MinSize:      util.ValueOrDefault(compressConf["min-size"], 1024).(int),
+		ContentTypes: stringList(compressConf["content-types"]),
+	})
+}
@@ -0,0 +1,251 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"wataru.com/gogo/config"
+	"wataru.com/gogo/frame/context"
+	"wataru.com/gogo/util"
+)
+
+// CorsConfig 跨域资源共享配置
+type CorsConfig struct {
+	// AllowedOrigins 允许的Origin，"*"为全部允许(不能与 AllowCredentials 同时使用)，支持通配子域名，如 https://*.example.com
+	AllowedOrigins []string
+	// AllowedMethods 预检请求允许的方法，为空时使用 GET, POST, PUT, PATCH, DELETE, HEAD
+	AllowedMethods []string
+	// AllowedHeaders 预检请求允许的请求头，为空时允许预检请求中的全部请求头
+	AllowedHeaders []string
+	// ExposedHeaders 允许浏览器读取的响应头
+	ExposedHeaders []string
+	// AllowCredentials 是否允许携带Cookie，为true时 AllowedOrigins 不能包含"*"
+	AllowCredentials bool
+	// MaxAge 预检请求结果的缓存时间，单位秒，为0时不设置
+	MaxAge int
+}
+
+// CorsMiddleware 跨域资源共享中间件，预检请求在中间件中直接响应，不执行处理函数
+// 未注册OPTIONS路由时预检请求只经过全局中间件，因此需配置在 server.middlewares 中
+//
+//	server:
+//	  middlewares: [cors, log, session]
+//	  cors:
+//	    allowed-origins: [https://www.example.com, https://*.example.com]
+//	    allowed-methods: [GET, POST, PUT, DELETE]
+//	    allowed-headers: [Content-Type, Authorization]
+//	    exposed-headers: [X-Total-Count]
+//	    allow-credentials: true
+//	    max-age: 3600
+type CorsMiddleware struct {
+	allowAll         bool
+	allowedOrigins   []string
+	allowedMethods   []string
+	allowedHeaders   []string
+	exposedHeaders   string
+	allowCredentials bool
+	maxAge           int
+}
+
+// Handle ...
+func (middleware CorsMiddleware) Handle(c *context.Context, next func()) {
+	origin := c.HttpRequest.Header.Get("Origin")
+	header := c.HttpResponse.ResponseWriter().Header()
+	header.Add("Vary", "Origin")
+	if origin == "" {
+		next()
+		return
+	}
+	requestMethod := c.HttpRequest.Header.Get("Access-Control-Request-Method")
+	if c.HttpRequest.Method == http.MethodOptions && requestMethod != "" {
+		middleware.preflight(c, origin, requestMethod)
+		return
+	}
+	if middleware.isOriginAllowed(origin) {
+		middleware.setAllowOrigin(c, origin)
+		if middleware.exposedHeaders != "" {
+			header.Set("Access-Control-Expose-Headers", middleware.exposedHeaders)
+		}
+	}
+	next()
+}
+
+// preflight 响应预检请求，Origin、方法或请求头不允许时返回403
+func (middleware CorsMiddleware) preflight(c *context.Context, origin, requestMethod string) {
+	header := c.HttpResponse.ResponseWriter().Header()
+	header.Add("Vary", "Access-Control-Request-Method")
+	header.Add("Vary", "Access-Control-Request-Headers")
+	requestHeaders := parseHeaderList(c.HttpRequest.Header.Get("Access-Control-Request-Headers"))
+	if !middleware.isOriginAllowed(origin) ||
+		!middleware.isMethodAllowed(requestMethod) ||
+		!middleware.areHeadersAllowed(requestHeaders) {
+		c.Abort(c.ErrorWithStatus(http.StatusForbidden, http.StatusText(http.StatusForbidden)))
+		return
+	}
+	middleware.setAllowOrigin(c, origin)
+	header.Set("Access-Control-Allow-Methods", strings.Join(middleware.allowedMethods, ", "))
+	if len(requestHeaders) > 0 {
+		// 允许的请求头为配置项时返回全部配置，未配置时原样返回预检请求中的请求头
+		if len(middleware.allowedHeaders) > 0 {
+			header.Set("Access-Control-Allow-Headers", strings.Join(middleware.allowedHeaders, ", "))
+		} else {
+			header.Set("Access-Control-Allow-Headers", strings.Join(requestHeaders, ", "))
+		}
+	}
+	if middleware.maxAge > 0 {
+		header.Set("Access-Control-Max-Age", strconv.Itoa(middleware.maxAge))
+	}
+	c.Abort(c.NoContent())
+}
+
+func (middleware CorsMiddleware) setAllowOrigin(c *context.Context, origin string) {
+	header := c.HttpResponse.ResponseWriter().Header()
+	if middleware.allowAll {
+		header.Set("Access-Control-Allow-Origin", "*")
+	} else {
+		header.Set("Access-Control-Allow-Origin", origin)
+	}
+	if middleware.allowCredentials {
+		header.Set("Access-Control-Allow-Credentials", "true")
+	}
+}
+
+func (middleware CorsMiddleware) isOriginAllowed(origin string) bool {
+	if middleware.allowAll {
+		return true
+	}
+	origin = strings.ToLower(origin)
+	for _, allowed := range middleware.allowedOrigins {
+		if matchOrigin(allowed, origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchOrigin 通配符 * 匹配非空的子域名，如 https://*.example.com 匹配 https://a.b.example.com，不匹配 https://example.com
+func matchOrigin(pattern, origin string) bool {
+	i := strings.IndexByte(pattern, '*')
+	if i < 0 {
+		return pattern == origin
+	}
+	prefix, suffix := pattern[:i], pattern[i+1:]
+	return len(origin) > len(prefix)+len(suffix) &&
+		strings.HasPrefix(origin, prefix) &&
+		strings.HasSuffix(origin, suffix)
+}
+
+func (middleware CorsMiddleware) isMethodAllowed(method string) bool {
+	method = strings.ToUpper(method)
+	if method == http.MethodOptions {
+		return true
+	}
+	for _, allowed := range middleware.allowedMethods {
+		if allowed == method {
+			return true
+		}
+	}
+	return false
+}
+
+func (middleware CorsMiddleware) areHeadersAllowed(headers []string) bool {
+	if len(middleware.allowedHeaders) == 0 {
+		return true
+	}
+	for _, h := range headers {
+		allowed := false
+		for _, ah := range middleware.allowedHeaders {
+			if ah == "*" || strings.EqualFold(ah, h) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return false
+		}
+	}
+	return true
+}
+
+func parseHeaderList(value string) []string {
+	headers := make([]string, 0)
+	for _, h := range strings.Split(value, ",") {
+		if h = strings.TrimSpace(h); h != "" {
+			headers = append(headers, http.CanonicalHeaderKey(h))
+		}
+	}
+	return headers
+}
+
+// NewCorsMiddleware 允许全部Origin且允许携带Cookie时panic，此时任意网站均可携带用户Cookie访问接口
+func NewCorsMiddleware(corsConfig CorsConfig) CorsMiddleware {
+	middleware := CorsMiddleware{
+		allowedMethods:   []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete, http.MethodHead},
+		exposedHeaders:   strings.Join(corsConfig.ExposedHeaders, ", "),
+		allowCredentials: corsConfig.AllowCredentials,
+		maxAge:           corsConfig.MaxAge,
+	}
+	for _, origin := range corsConfig.AllowedOrigins {
+		if origin == "*" {
+			middleware.allowAll = true
+		}
+		middleware.allowedOrigins = append(middleware.allowedOrigins, strings.ToLower(origin))
+	}
+	if middleware.allowAll && middleware.allowCredentials {
+		panic("Cors allowed origins '*' can not be used with allow credentials")
+	}
+	if len(corsConfig.AllowedMethods) > 0 {
+		middleware.allowedMethods = make([]string, 0, len(corsConfig.AllowedMethods))
+		for _, method := range corsConfig.AllowedMethods {
+			middleware.allowedMethods = append(middleware.allowedMethods, strings.ToUpper(method))
+		}
+	}
+	for _, h := range corsConfig.AllowedHeaders {
+		if h == "*" {
+			// 允许全部请求头，与未配置相同
+			middleware.allowedHeaders = nil
+			break
+		}
+		middleware.allowedHeaders = append(middleware.allowedHeaders, http.CanonicalHeaderKey(h))
+	}
+	return middleware
+}
+
+// NewCorsMiddlewareFromConfig 读取 server.cors 配置创建跨域中间件，必须配置 allowed-origins，允许全部Origin时需显式配置为 "*"
+func NewCorsMiddlewareFromConfig() CorsMiddleware {
+	serverConf := util.ValueOrDefault((*config.GlobalConfig.Map)["server"], make(map[interface{}]interface{})).(map[interface{}]interface{})
+	corsConf := util.ValueOrDefault(serverConf["cors"], make(map[interface{}]interface{})).(map[interface{}]interface{})
+	allowedOrigins := stringList(corsConf["allowed-origins"])
+	if len(allowedOrigins) == 0 {
+		panic("Cors middleware requires server.cors.allowed-origins")
+	}
+	return NewCorsMiddleware(CorsConfig{
+		AllowedOrigins:   allowedOrigins,
+		AllowedMethods:   stringList(corsConf["allowed-methods"]),
+		AllowedHeaders:   stringList(corsConf["allowed-headers"]),
+		ExposedHeaders:   stringList(corsConf["exposed-headers"]),
+		AllowCredentials: util.ValueOrDefault(corsConf["allow-credentials"], false).(bool),
+		MaxAge:           util.ValueOrDefault(corsConf["max-age"], 0).(int),
+	})
+}
+
+// stringList 读取字符串列表配置，兼容逗号分隔的字符串
+func stringList(value interface{}) []string {
+	list := make([]string, 0)
+	switch v := value.(type) {
+	case []interface{}:
+		for _, item := range v {
+			if s, ok := item.(string); ok && strings.TrimSpace(s) != "" {
+				list = append(list, strings.TrimSpace(s))
+			}
+		}
+	case string:
+		for _, s := range strings.Split(v, ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				list = append(list, s)
+			}
+		}
+	}
+	return list
+}
@@ -23,6 +23,13 @@ func (middleware LogMiddleware) After(c *context.Context) {
 		float64(time.Now().UnixNano()/1000000-c.LocalVars.Get("start_request_time").(int64)))
 }
 
+// Handle 洋葱模型入口，等价于 Adapt(LogMiddleware{})，兼容通过 router.Middleware 注册的用法
+func (middleware LogMiddleware) Handle(c *context.Context, next func()) {
+	middleware.Before(c)
+	next()
+	middleware.After(c)
+}
+
 // NewLogMiddleware ...
 func NewLogMiddleware() LogMiddleware {
 	return LogMiddleware{}
@@ -0,0 +1,268 @@
+package middleware
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"wataru.com/gogo/config"
+	"wataru.com/gogo/frame/context"
+	"wataru.com/gogo/logger"
+	"wataru.com/gogo/redis"
+	"wataru.com/gogo/util"
+)
+
+// RateLimitKeyFunc 返回限流的key，如客户端IP、会话ID或用户ID，返回空字符串时不限流
+type RateLimitKeyFunc func(c *context.Context) string
+
+// RateLimitByIP 按客户端IP限流，使用 Context.RealIP，通过反向代理部署时需配置 server.trusted-proxies
+func RateLimitByIP(c *context.Context) string {
+	return c.RealIP()
+}
+
+// RateLimitBySession 按会话限流，需在session中间件之后执行，无会话或新建的会话按客户端IP限流，
+// 避免不携带Cookie的请求每次获得新的令牌桶
+func RateLimitBySession(c *context.Context) string {
+	if c.Session != nil && !c.Session.IsNew {
+		return "session:" + c.Session.Id
+	}
+	return c.RealIP()
+}
+
+var (
+	rateLimitKeysMu sync.RWMutex
+	rateLimitKeys   = map[string]RateLimitKeyFunc{
+		"ip":      RateLimitByIP,
+		"session": RateLimitBySession,
+	}
+)
+
+// RegistRateLimitKey 注册限流key函数，注册后可在 server.rate-limit.key 中使用
+//
+//	middleware.RegistRateLimitKey("user", func(c *context.Context) string {
+//		return fmt.Sprintf("%v", c.Session.GetAttribute("userId"))
+//	})
+func RegistRateLimitKey(name string, fn RateLimitKeyFunc) {
+	rateLimitKeysMu.Lock()
+	defer rateLimitKeysMu.Unlock()
+	if _, ok := rateLimitKeys[name]; ok {
+		panic("Rate limit key named " + name + " alrealy exists!")
+	}
+	rateLimitKeys[name] = fn
+}
+
+func lookupRateLimitKey(name string) RateLimitKeyFunc {
+	rateLimitKeysMu.RLock()
+	defer rateLimitKeysMu.RUnlock()
+	fn, ok := rateLimitKeys[name]
+	if !ok {
+		panic("Rate limit key named " + name + " does not exists!")
+	}
+	return fn
+}
+
+// RateLimitRule 限流规则
+type RateLimitRule struct {
+	// Pattern 路由路径，如 /user/:id，以*结尾时匹配该前缀的全部路由并共享令牌桶，为空时匹配全部路由且每个路由单独限流
+	Pattern string
+	// Methods 限流的HTTP方法，为空时为全部方法
+	Methods []string
+	RateLimit
+}
+
+func (rule RateLimitRule) match(c *context.Context) bool {
+	if len(rule.Methods) > 0 && !containsMethod(rule.Methods, c.HttpRequest.Method) {
+		return false
+	}
+	if rule.Pattern == "" {
+		return true
+	}
+	if strings.HasSuffix(rule.Pattern, "*") {
+		return strings.HasPrefix(c.RoutePattern, strings.TrimSuffix(rule.Pattern, "*"))
+	}
+	return rule.Pattern == c.RoutePattern
+}
+
+func containsMethod(methods []string, method string) bool {
+	for _, m := range methods {
+		if strings.EqualFold(m, method) {
+			return true
+		}
+	}
+	return false
+}
+
+// RateLimitConfig 限流配置
+type RateLimitConfig struct {
+	// Rules 限流规则，使用第一个匹配的规则，未匹配时不限流
+	Rules []RateLimitRule
+	// Store 令牌桶存储，为nil时使用进程内存储
+	Store RateLimitStore
+	// KeyFunc 限流key，为nil时按客户端IP限流
+	KeyFunc RateLimitKeyFunc
+	// Message 超出限制时的提示信息
+	Message string
+}
+
+// RateLimitMiddleware 令牌桶限流中间件，超出限制时返回429及 Retry-After 响应头
+// 响应头 X-RateLimit-Limit 为令牌桶容量，X-RateLimit-Remaining 为剩余令牌数，X-RateLimit-Reset 为令牌桶补满的秒数
+//
+//	server:
+//	  middlewares: [log, session, ratelimit]
+//	  trusted-proxies: [10.0.0.0/8]
+//	  rate-limit:
+//	    store: redis
+//	    key: session
+//	    rules:
+//	      - pattern: /login
+//	        methods: [POST]
+//	        rate: 5
+//	        period: 1m
+//	      - pattern: /api/*
+//	        rate: 100
+//	        period: 1s
+//	        burst: 200
+//
+// 也可作为路由中间件使用，规则未设置Pattern时对该路由限流
+//
+//	router.Post("/sms/code", sendSmsCode, middleware.NewRateLimitMiddleware(middleware.RateLimitConfig{
+//		Rules: []middleware.RateLimitRule{{RateLimit: middleware.RateLimit{Rate: 1, Period: time.Minute}}},
+//	}))
+type RateLimitMiddleware struct {
+	rules   []RateLimitRule
+	store   RateLimitStore
+	keyFunc RateLimitKeyFunc
+	message string
+}
+
+// Handle ...
+func (middleware RateLimitMiddleware) Handle(c *context.Context, next func()) {
+	rule, ok := middleware.matchRule(c)
+	if !ok {
+		next()
+		return
+	}
+	key := middleware.keyFunc(c)
+	if key == "" {
+		next()
+		return
+	}
+	pattern := rule.Pattern
+	if pattern == "" {
+		pattern = c.RoutePattern
+	}
+	if len(rule.Methods) > 0 {
+		pattern = c.HttpRequest.Method + " " + pattern
+	}
+	result, err := middleware.store.Take(pattern+"|"+key, rule.RateLimit)
+	if err != nil {
+		// 存储不可用时不限流
+		logger.Error("Rate limit failed for [%s]: %v", c.HttpRequest.Uri(), err)
+		next()
+		return
+	}
+	header := c.HttpResponse.ResponseWriter().Header()
+	header.Set("X-RateLimit-Limit", strconv.Itoa(rule.burst()))
+	header.Set("X-RateLimit-Remaining", strconv.Itoa(result.Remaining))
+	header.Set("X-RateLimit-Reset", strconv.Itoa(ceilSeconds(result.Reset)))
+	if !result.Allowed {
+		header.Set("Retry-After", strconv.Itoa(ceilSeconds(result.RetryAfter)))
+		c.Abort(c.ErrorWithStatus(http.StatusTooManyRequests, middleware.message))
+		return
+	}
+	next()
+}
+
+func (middleware RateLimitMiddleware) matchRule(c *context.Context) (RateLimitRule, bool) {
+	for _, rule := range middleware.rules {
+		if rule.match(c) {
+			return rule, true
+		}
+	}
+	return RateLimitRule{}, false
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
+
+// NewRateLimitMiddleware ...
+func NewRateLimitMiddleware(rateLimitConfig RateLimitConfig) RateLimitMiddleware {
+	for _, rule := range rateLimitConfig.Rules {
+		if rule.Rate <= 0 || rule.Period <= 0 {
+			panic(fmt.Sprintf("Rate limit rule '%s' must have positive rate and period", rule.Pattern))
+		}
+	}
+	middleware := RateLimitMiddleware{
+		rules:   rateLimitConfig.Rules,
+		store:   rateLimitConfig.Store,
+		keyFunc: rateLimitConfig.KeyFunc,
+		message: rateLimitConfig.Message,
+	}
+	if middleware.store == nil {
+		middleware.store = NewMemoryRateLimitStore()
+	}
+	if middleware.keyFunc == nil {
+		middleware.keyFunc = RateLimitByIP
+	}
+	if middleware.message == "" {
+		middleware.message = "请求过于频繁，请稍后再试"
+	}
+	return middleware
+}
+
+// NewRateLimitMiddlewareFromConfig 读取 server.rate-limit 配置创建限流中间件，store为redis时使用 redis.Rdb
+func NewRateLimitMiddlewareFromConfig() RateLimitMiddleware {
+	serverConf := util.ValueOrDefault((*config.GlobalConfig.Map)["server"], make(map[interface{}]interface{})).(map[interface{}]interface{})
+	rateLimitConf := util.ValueOrDefault(serverConf["rate-limit"], make(map[interface{}]interface{})).(map[interface{}]interface{})
+	rateLimitConfig := RateLimitConfig{
+		KeyFunc: lookupRateLimitKey(util.ValueOrDefault(rateLimitConf["key"], "ip").(string)),
+		Message: util.ValueOrDefault(rateLimitConf["message"], "").(string),
+	}
+	switch store := util.ValueOrDefault(rateLimitConf["store"], "memory").(string); store {
+	case "memory":
+	case "redis":
+		// 输出路由表时不连接Redis
+		if redis.Rdb == nil && !config.GlobalConfig.PrintRoutes {
+			panic("Rate limit store redis requires redis config")
+		}
+		rateLimitConfig.Store = NewRedisRateLimitStore(redis.Rdb, util.ValueOrDefault(rateLimitConf["prefix"], "").(string))
+	default:
+		panic("Rate limit store " + store + " does not exists!")
+	}
+	rules, _ := rateLimitConf["rules"].([]interface{})
+	for _, r := range rules {
+		ruleConf := r.(map[interface{}]interface{})
+		rateLimitConfig.Rules = append(rateLimitConfig.Rules, RateLimitRule{
+			Pattern: util.ValueOrDefault(ruleConf["pattern"], "").(string),
+			Methods: stringList(ruleConf["methods"]),
+			RateLimit: RateLimit{
+				Rate:   util.ValueOrDefault(ruleConf["rate"], 0).(int),
+				Period: configPeriod(ruleConf["period"]),
+				Burst:  util.ValueOrDefault(ruleConf["burst"], 0).(int),
+			},
+		})
+	}
+	return NewRateLimitMiddleware(rateLimitConfig)
+}
+
+// configPeriod 读取时间配置，字符串格式见 time.ParseDuration，数字为秒数，未配置时为1秒
+func configPeriod(value interface{}) time.Duration {
+	switch v := value.(type) {
+	case nil:
+		return time.Second
+	case int:
+		return time.Duration(v) * time.Second
+	case string:
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			panic("Invalid rate limit period '" + v + "': " + err.Error())
+		}
+		return d
+	}
+	panic(fmt.Sprintf("Invalid rate limit period '%v'", value))
+}
@@ -0,0 +1,187 @@
+package middleware
+
+import (
+	gocontext "context"
+	"fmt"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	goredis "github.com/go-redis/redis/v8"
+)
+
+// RateLimit 令牌桶参数，每个Period补充Rate个令牌，桶容量为Burst
+type RateLimit struct {
+	Rate   int
+	Period time.Duration
+	// Burst 桶容量，即允许的突发请求数，为0时等于Rate
+	Burst int
+}
+
+// tokensPerSecond 每秒补充的令牌数
+func (limit RateLimit) tokensPerSecond() float64 {
+	return float64(limit.Rate) / limit.Period.Seconds()
+}
+
+func (limit RateLimit) burst() int {
+	if limit.Burst > 0 {
+		return limit.Burst
+	}
+	return limit.Rate
+}
+
+// RateLimitResult 取令牌的结果
+type RateLimitResult struct {
+	Allowed bool
+	// Remaining 剩余令牌数
+	Remaining int
+	// RetryAfter 请求被拒绝时，距下一个令牌可用的时间
+	RetryAfter time.Duration
+	// Reset 距令牌桶补满的时间
+	Reset time.Duration
+}
+
+// newRateLimitResult 根据取令牌后剩余的令牌数计算结果
+func newRateLimitResult(allowed bool, tokens float64, limit RateLimit) RateLimitResult {
+	rate := limit.tokensPerSecond()
+	result := RateLimitResult{
+		Allowed:   allowed,
+		Remaining: int(math.Floor(tokens)),
+		Reset:     time.Duration((float64(limit.burst()) - tokens) / rate * float64(time.Second)),
+	}
+	if !allowed {
+		result.RetryAfter = time.Duration((1 - tokens) / rate * float64(time.Second))
+	}
+	return result
+}
+
+// RateLimitStore 令牌桶存储
+type RateLimitStore interface {
+	// Take 从key对应的令牌桶中取出一个令牌
+	Take(key string, limit RateLimit) (RateLimitResult, error)
+}
+
+// MemoryRateLimitStore 进程内令牌桶存储，适用于单节点部署
+type MemoryRateLimitStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*tokenBucket
+	lastSweep time.Time
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+	// full 令牌桶补满的时间，之后可删除该令牌桶
+	full time.Time
+}
+
+// rateLimitSweepInterval 清理已补满的令牌桶的间隔
+const rateLimitSweepInterval = time.Minute
+
+// NewMemoryRateLimitStore ...
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{
+		buckets:   make(map[string]*tokenBucket),
+		lastSweep: time.Now(),
+	}
+}
+
+// Take ...
+func (store *MemoryRateLimitStore) Take(key string, limit RateLimit) (RateLimitResult, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	now := time.Now()
+	if now.Sub(store.lastSweep) > rateLimitSweepInterval {
+		store.sweep(now)
+	}
+	rate := limit.tokensPerSecond()
+	burst := float64(limit.burst())
+	bucket := store.buckets[key]
+	if bucket == nil {
+		bucket = &tokenBucket{tokens: burst, last: now}
+		store.buckets[key] = bucket
+	}
+	bucket.tokens = math.Min(burst, bucket.tokens+now.Sub(bucket.last).Seconds()*rate)
+	bucket.last = now
+	allowed := bucket.tokens >= 1
+	if allowed {
+		bucket.tokens--
+	}
+	result := newRateLimitResult(allowed, bucket.tokens, limit)
+	bucket.full = now.Add(result.Reset)
+	return result, nil
+}
+
+// sweep 删除已补满的令牌桶，与新建的令牌桶等价
+func (store *MemoryRateLimitStore) sweep(now time.Time) {
+	for key, bucket := range store.buckets {
+		if !now.Before(bucket.full) {
+			delete(store.buckets, key)
+		}
+	}
+	store.lastSweep = now
+}
+
+// RedisRateLimitStore 基于Redis的令牌桶存储，适用于多节点部署，使用Redis服务器时间计算令牌补充
+type RedisRateLimitStore struct {
+	client *goredis.Client
+	prefix string
+}
+
+// tokenBucketScript 原子地补充并取出令牌，返回是否允许及剩余令牌数
+// 令牌桶在补满后过期，Lua返回的小数会被截断，剩余令牌数以字符串返回
+var tokenBucketScript = goredis.NewScript(`
+redis.replicate_commands()
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local time = redis.call('TIME')
+local now = tonumber(time[1]) + tonumber(time[2]) / 1000000
+local bucket = redis.call('HMGET', KEYS[1], 'tokens', 'last')
+local tokens = tonumber(bucket[1])
+local last = tonumber(bucket[2])
+if tokens == nil or last == nil then
+	tokens = burst
+	last = now
+end
+tokens = math.min(burst, tokens + math.max(0, now - last) * rate)
+local allowed = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+end
+redis.call('HMSET', KEYS[1], 'tokens', tostring(tokens), 'last', tostring(now))
+redis.call('EXPIRE', KEYS[1], math.ceil((burst - tokens) / rate) + 1)
+return {allowed, tostring(tokens)}
+`)
+
+// NewRedisRateLimitStore prefix为令牌桶key的前缀，为空时使用 ratelimit:
+func NewRedisRateLimitStore(client *goredis.Client, prefix string) *RedisRateLimitStore {
+	if prefix == "" {
+		prefix = "ratelimit:"
+	}
+	return &RedisRateLimitStore{
+		client: client,
+		prefix: prefix,
+	}
+}
+
+// Take ...
+func (store *RedisRateLimitStore) Take(key string, limit RateLimit) (RateLimitResult, error) {
+	rate := strconv.FormatFloat(limit.tokensPerSecond(), 'f', -1, 64)
+	reply, err := tokenBucketScript.Run(gocontext.Background(), store.client,
+		[]string{store.prefix + key}, rate, limit.burst()).Result()
+	if err != nil {
+		return RateLimitResult{}, err
+	}
+	values, ok := reply.([]interface{})
+	if !ok || len(values) != 2 {
+		return RateLimitResult{}, fmt.Errorf("unexpected rate limit script reply: %v", reply)
+	}
+	allowed, _ := values[0].(int64)
+	tokens, err := strconv.ParseFloat(fmt.Sprintf("%v", values[1]), 64)
+	if err != nil {
+		return RateLimitResult{}, err
+	}
+	return newRateLimitResult(allowed == 1, tokens, limit), nil
+}
@@ -0,0 +1,55 @@
+package middleware
+
+import (
+	"sync"
+)
+
+// Factory 创建中间件实例
+type Factory func() Handler
+
+// DefaultMiddlewares 未配置 server.middlewares 时启用的全局中间件
+var DefaultMiddlewares = []string{"log", "session"}
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[string]Factory)
+)
+
+// Regist 注册命名中间件，注册后可在 config.yml 中通过 server.middlewares 启用
+//
+//	server:
+//	  middlewares: [log, session]
+func Regist(name string, factory Factory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if _, ok := registry[name]; ok {
+		panic("Middleware named " + name + " alrealy exists!")
+	}
+	registry[name] = factory
+}
+
+// Lookup 查找命名中间件
+func Lookup(name string) (Factory, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	factory, ok := registry[name]
+	return factory, ok
+}
+
+func init() {
+	Regist("log", func() Handler {
+		return NewLogMiddleware()
+	})
+	Regist("session", func() Handler {
+		return Adapt(NewSessionMiddleware())
+	})
+	Regist("cors", func() Handler {
+		return NewCorsMiddlewareFromConfig()
+	})
+	Regist("compress", func() Handler {
+		return NewCompressMiddlewareFromConfig()
+	})
+	Regist("ratelimit", func() Handler {
+		return NewRateLimitMiddlewareFromConfig()
+	})
+}
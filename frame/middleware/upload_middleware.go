@@ -0,0 +1,27 @@
+package middleware
+
+import (
+	"wataru.com/gogo/frame/context"
+)
+
+// UploadMiddleware 设置路由的文件上传限制，覆盖 server.upload 配置
+//
+//	router.Post("/document", uploadDocument, middleware.NewUploadMiddleware(context.UploadLimit{
+//		MaxSize:      100 << 20,
+//		MaxFiles:     5,
+//		AllowedTypes: []string{"application/pdf", "image/*"},
+//	}))
+type UploadMiddleware struct {
+	Limit context.UploadLimit
+}
+
+// Handle ...
+func (middleware UploadMiddleware) Handle(c *context.Context, next func()) {
+	c.SetUploadLimit(middleware.Limit)
+	next()
+}
+
+// NewUploadMiddleware ...
+func NewUploadMiddleware(limit context.UploadLimit) UploadMiddleware {
+	return UploadMiddleware{Limit: limit}
+}
@@ -3,6 +3,7 @@ package panics
 type BizPanic struct {
 	Message string
 	Code    int
+	Status  int // HTTP状态码，为0时默认200
 }
 
 func (p BizPanic) String() string {
@@ -22,3 +23,21 @@ func NewBizPanicWithCode(message string, code int) *BizPanic {
 		Code:    code,
 	}
 }
+
+func NewBizPanicWithStatus(message string, status int) *BizPanic {
+	return &BizPanic{
+		Message: message,
+		Code:    -1,
+		Status:  status,
+	}
+}
+
+// Error 实现error接口，处理函数可直接返回BizPanic
+func (p *BizPanic) Error() string {
+	return p.Message
+}
+
+// StatusCode HTTP状态码，为0时默认200
+func (p *BizPanic) StatusCode() int {
+	return p.Status
+}
@@ -0,0 +1,508 @@
+package router
+
+import (
+	"encoding/json"
+	"mime/multipart"
+	"path"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v2"
+	"wataru.com/gogo/config"
+	"wataru.com/gogo/frame/context"
+	"wataru.com/gogo/frame/router/swaggerui"
+	"wataru.com/gogo/util"
+)
+
+// Doc 设置路由的接口文档摘要及分组标签
+//
+//	router.Post("/user", createUser).Doc("创建用户", "user").Request(&CreateUserReq{}).Response(&User{})
+func (fn *HandlerFunc) Doc(summary string, tags ...string) *HandlerFunc {
+	fn.summary = summary
+	fn.tags = tags
+	return fn
+}
+
+// Request 声明请求参数类型，GET、DELETE等无请求体的方法生成查询参数，其余生成JSON请求体
+func (fn *HandlerFunc) Request(req interface{}) *HandlerFunc {
+	fn.reqType = reflect.TypeOf(req)
+	return fn
+}
+
+// Response 声明响应数据类型，即 Context.Success 中data的类型
+func (fn *HandlerFunc) Response(resp interface{}) *HandlerFunc {
+	fn.respType = reflect.TypeOf(resp)
+	return fn
+}
+
+// OpenAPI 根据已注册的路由生成OpenAPI 3文档
+func (router *Router) OpenAPI(title, version string) map[string]interface{} {
+	b := &schemaBuilder{
+		schemas: make(map[string]interface{}),
+		types:   make(map[string]reflect.Type),
+	}
+	paths := make(map[string]interface{})
+	for _, fn := range router.sortedHandlerFuncs() {
+		if fn.hidden {
+			continue
+		}
+		item, ok := paths[openAPIPath(fn.segments)].(map[string]interface{})
+		if !ok {
+			item = make(map[string]interface{})
+			paths[openAPIPath(fn.segments)] = item
+		}
+		methods := []string{strings.ToLower(string(fn.httpMethodType))}
+		if fn.httpMethodType == ALL {
+			methods = []string{"get", "post", "put", "delete", "patch"}
+		}
+		for _, method := range methods {
+			op := b.operation(fn, method)
+			// 同一路由生成多个操作时operationId需唯一
+			if len(methods) > 1 {
+				op["operationId"] = op["operationId"].(string) + "_" + method
+			}
+			item[method] = op
+		}
+	}
+	return map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]interface{}{
+			"title":   title,
+			"version": version,
+		},
+		"paths": paths,
+		"components": map[string]interface{}{
+			"schemas": b.schemas,
+		},
+	}
+}
+
+// openAPIPath 将 /user/:id 转换为 /user/{id}
+func openAPIPath(segments []segment) string {
+	var sb strings.Builder
+	for _, seg := range segments {
+		sb.WriteByte('/')
+		if seg.kind == staticSegment {
+			sb.WriteString(seg.name)
+		} else {
+			sb.WriteString("{" + seg.name + "}")
+		}
+	}
+	return sb.String()
+}
+
+type schemaBuilder struct {
+	schemas map[string]interface{}
+	types   map[string]reflect.Type
+}
+
+func (b *schemaBuilder) operation(fn *HandlerFunc, method string) map[string]interface{} {
+	operationID := fn.name
+	if operationID == "" {
+		operationID = method + strings.NewReplacer("/", "_", ":", "", "*", "").Replace(fn.pattern)
+	}
+	op := map[string]interface{}{
+		"operationId": operationID,
+		"responses": map[string]interface{}{
+			"200": map[string]interface{}{
+				"description": "OK",
+				"content": map[string]interface{}{
+					"application/json": map[string]interface{}{
+						"schema": b.envelope(fn.respType),
+					},
+				},
+			},
+		},
+	}
+	if fn.summary != "" {
+		op["summary"] = fn.summary
+	}
+	if len(fn.tags) > 0 {
+		op["tags"] = fn.tags
+	}
+	parameters := []interface{}{}
+	for _, seg := range fn.segments {
+		if seg.kind != staticSegment {
+			parameters = append(parameters, map[string]interface{}{
+				"name":     seg.name,
+				"in":       "path",
+				"required": true,
+				"schema":   map[string]interface{}{"type": "string"},
+			})
+		}
+	}
+	if fn.reqType != nil {
+		reqType := indirectType(fn.reqType)
+		if reqType.Kind() == reflect.Struct {
+			parameters = append(parameters, b.parameters(reqType, method)...)
+		}
+		if hasRequestBody(method) {
+			mediaType := "application/json"
+			if hasFileField(reqType) {
+				mediaType = "multipart/form-data"
+			}
+			op["requestBody"] = map[string]interface{}{
+				"required": true,
+				"content": map[string]interface{}{
+					mediaType: map[string]interface{}{
+						"schema": b.schemaOf(fn.reqType),
+					},
+				},
+			}
+		}
+	}
+	if len(parameters) > 0 {
+		op["parameters"] = parameters
+	}
+	return op
+}
+
+func hasRequestBody(method string) bool {
+	return method == "post" || method == "put" || method == "patch"
+}
+
+// envelope 响应结构 context.Response
+func (b *schemaBuilder) envelope(respType reflect.Type) map[string]interface{} {
+	data := map[string]interface{}{}
+	if respType != nil {
+		data = b.schemaOf(respType)
+	}
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"data":    data,
+			"code":    map[string]interface{}{"type": "integer"},
+			"success": map[string]interface{}{"type": "boolean"},
+			"message": map[string]interface{}{"type": "string"},
+		},
+	}
+}
+
+// parameters 请求结构中设置了form tag的查询参数及设置了header tag的请求头参数，有请求体的方法只生成请求头参数
+func (b *schemaBuilder) parameters(t reflect.Type, method string) []interface{} {
+	parameters := []interface{}{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		if field.Anonymous && indirectType(field.Type).Kind() == reflect.Struct {
+			parameters = append(parameters, b.parameters(indirectType(field.Type), method)...)
+			continue
+		}
+		if _, ok := field.Tag.Lookup("uri"); ok {
+			continue
+		}
+		// 与 Context.ShouldBindRequest 一致，只有设置了header或form的字段为参数
+		var in, name string
+		if header, ok := field.Tag.Lookup("header"); ok {
+			in, name = "header", strings.Split(header, ",")[0]
+		} else if form, ok := field.Tag.Lookup("form"); ok && !hasRequestBody(method) {
+			in, name = "query", strings.Split(form, ",")[0]
+		} else {
+			continue
+		}
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		schema := b.schemaOf(field.Type)
+		required := applyBindingTag(schema, field.Tag.Get("binding"))
+		parameters = append(parameters, map[string]interface{}{
+			"name":     name,
+			"in":       in,
+			"required": required,
+			"schema":   schema,
+		})
+	}
+	return parameters
+}
+
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	fileHeaderType = reflect.TypeOf(multipart.FileHeader{})
+)
+
+// hasFileField 请求结构是否包含上传文件字段
+func hasFileField(t reflect.Type) bool {
+	if t.Kind() != reflect.Struct {
+		return false
+	}
+	for i := 0; i < t.NumField(); i++ {
+		ft := indirectType(t.Field(i).Type)
+		if ft.Kind() == reflect.Slice {
+			ft = indirectType(ft.Elem())
+		}
+		if ft == fileHeaderType || (t.Field(i).Anonymous && hasFileField(ft)) {
+			return true
+		}
+	}
+	return false
+}
+
+func indirectType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t
+}
+
+// schemaOf 生成类型的JSON Schema，具名结构体放入components中并返回引用
+func (b *schemaBuilder) schemaOf(t reflect.Type) map[string]interface{} {
+	t = indirectType(t)
+	if t == timeType {
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	}
+	if t == fileHeaderType {
+		return map[string]interface{}{"type": "string", "format": "binary"}
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return map[string]interface{}{"type": "integer", "format": "int32"}
+	case reflect.Int64, reflect.Uint, reflect.Uint64:
+		return map[string]interface{}{"type": "integer", "format": "int64"}
+	case reflect.Float32:
+		return map[string]interface{}{"type": "number", "format": "float"}
+	case reflect.Float64:
+		return map[string]interface{}{"type": "number", "format": "double"}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]interface{}{"type": "string", "format": "byte"}
+		}
+		return map[string]interface{}{"type": "array", "items": b.schemaOf(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": b.schemaOf(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return b.structSchema(t)
+		}
+		name := b.componentName(t)
+		if _, ok := b.schemas[name]; !ok {
+			// 先占位，避免递归类型无限展开
+			b.schemas[name] = map[string]interface{}{}
+			b.schemas[name] = b.structSchema(t)
+		}
+		return map[string]interface{}{"$ref": "#/components/schemas/" + name}
+	}
+	return map[string]interface{}{}
+}
+
+// componentName 组件名称，不同包的同名类型加上包名区分
+func (b *schemaBuilder) componentName(t reflect.Type) string {
+	name := t.Name()
+	if exist, ok := b.types[name]; ok && exist != t {
+		name = path.Base(t.PkgPath()) + "." + name
+	}
+	b.types[name] = t
+	return name
+}
+
+func (b *schemaBuilder) structSchema(t reflect.Type) map[string]interface{} {
+	properties := make(map[string]interface{})
+	required := []string{}
+	b.collectProperties(t, properties, &required)
+	schema := map[string]interface{}{
+		"type":       "object",
+		"properties": properties,
+	}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+func (b *schemaBuilder) collectProperties(t reflect.Type, properties map[string]interface{}, required *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		jsonTag := field.Tag.Get("json")
+		if field.Anonymous && jsonTag == "" && indirectType(field.Type).Kind() == reflect.Struct {
+			b.collectProperties(indirectType(field.Type), properties, required)
+			continue
+		}
+		name := fieldName(field, "json")
+		if name == "-" {
+			continue
+		}
+		schema := b.schemaOf(field.Type)
+		if _, isRef := schema["$ref"]; !isRef && applyBindingTag(schema, field.Tag.Get("binding")) {
+			*required = append(*required, name)
+		} else if isRef && hasBindingRule(field.Tag.Get("binding"), "required") {
+			*required = append(*required, name)
+		}
+		properties[name] = schema
+	}
+}
+
+// fieldName 字段名称，依次取指定标签、json标签、字段名
+func fieldName(field reflect.StructField, tagName string) string {
+	for _, tag := range []string{field.Tag.Get(tagName), field.Tag.Get("json")} {
+		if name := strings.Split(tag, ",")[0]; name != "" {
+			return name
+		}
+	}
+	return field.Name
+}
+
+func hasBindingRule(binding, rule string) bool {
+	for _, item := range strings.Split(binding, ",") {
+		if item == rule {
+			return true
+		}
+	}
+	return false
+}
+
+// applyBindingTag 将validator的binding标签转换为Schema约束，返回是否必填
+func applyBindingTag(schema map[string]interface{}, binding string) bool {
+	required := false
+	for _, rule := range strings.Split(binding, ",") {
+		key, value := rule, ""
+		if i := strings.IndexByte(rule, '='); i >= 0 {
+			key, value = rule[:i], rule[i+1:]
+		}
+		switch key {
+		case "required":
+			required = true
+		case "email", "uuid", "ipv4", "ipv6", "hostname":
+			schema["format"] = key
+		case "url", "uri":
+			schema["format"] = "uri"
+		case "oneof":
+			enum := []interface{}{}
+			for _, item := range strings.Fields(value) {
+				enum = append(enum, item)
+			}
+			schema["enum"] = enum
+		case "min", "max", "len", "gt", "gte", "lt", "lte":
+			n, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			applyLimit(schema, key, n)
+		}
+	}
+	return required
+}
+
+func applyLimit(schema map[string]interface{}, key string, n float64) {
+	prefix := ""
+	switch schema["type"] {
+	case "string":
+		prefix = "Length"
+	case "array":
+		prefix = "Items"
+	case "object":
+		prefix = "Properties"
+	}
+	if prefix != "" {
+		switch key {
+		case "min", "gte":
+			schema["min"+prefix] = int(n)
+		case "max", "lte":
+			schema["max"+prefix] = int(n)
+		case "len":
+			schema["min"+prefix] = int(n)
+			schema["max"+prefix] = int(n)
+		}
+		return
+	}
+	switch key {
+	case "min", "gte":
+		schema["minimum"] = n
+	case "max", "lte":
+		schema["maximum"] = n
+	case "gt":
+		schema["minimum"] = n
+		schema["exclusiveMinimum"] = true
+	case "lt":
+		schema["maximum"] = n
+		schema["exclusiveMaximum"] = true
+	}
+}
+
+const swaggerUIPage = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{TITLE}}</title>
+<link rel="stylesheet" href="{{UI}}/swagger-ui.css">
+</head>
+<body>
+<div id="swagger-ui"></div>
+<script src="{{UI}}/swagger-ui-bundle.js"></script>
+<script>
+window.onload = function() {
+  window.ui = SwaggerUIBundle({url: "{{DOC}}", dom_id: "#swagger-ui"});
+};
+</script>
+</body>
+</html>
+`
+
+// loadOpenAPIEndpoint 配置 server.openapi.path 时注册文档接口，path.json 返回JSON，path.yaml 返回YAML
+// 配置 ui-path 时在该路径提供内置的Swagger UI页面，见 swaggerui 包；
+// 配置 ui-dir 时改为按 外部路径 -> ./resources/ -> 可执行文件资源 的顺序从该目录查找 swagger-ui-bundle.js、swagger-ui.css
+//
+//	server:
+//	  openapi:
+//	    path: /openapi
+//	    title: demo
+//	    version: 1.0.0
+//	    ui-path: /swagger-ui
+//	    ui-dir: swagger-ui  // 可选，使用自定义的Swagger UI资源
+func (router *Router) loadOpenAPIEndpoint() {
+	serverConf := util.ValueOrDefault((*config.GlobalConfig.Map)["server"], make(map[interface{}]interface{})).(map[interface{}]interface{})
+	openAPIConf := util.ValueOrDefault(serverConf["openapi"], make(map[interface{}]interface{})).(map[interface{}]interface{})
+	docPath := util.ValueOrDefault(openAPIConf["path"], "").(string)
+	if docPath == "" {
+		return
+	}
+	title := util.ValueOrDefault(openAPIConf["title"], "GOGO API").(string)
+	version := util.ValueOrDefault(openAPIConf["version"], "1.0.0").(string)
+	var once sync.Once
+	var jsonDoc, yamlDoc []byte
+	generate := func() {
+		once.Do(func() {
+			doc := router.OpenAPI(title, version)
+			jsonDoc, _ = json.MarshalIndent(doc, "", "  ")
+			yamlDoc, _ = yaml.Marshal(doc)
+		})
+	}
+	router.Get(docPath+".json", func(c *context.Context) interface{} {
+		generate()
+		c.Header("Content-Type", "application/json; charset=utf-8")
+		return jsonDoc
+	}).hidden = true
+	router.Get(docPath+".yaml", func(c *context.Context) interface{} {
+		generate()
+		c.Header("Content-Type", "application/yaml; charset=utf-8")
+		return yamlDoc
+	}).hidden = true
+
+	uiPath := util.ValueOrDefault(openAPIConf["ui-path"], "").(string)
+	if uiPath == "" {
+		return
+	}
+	page := strings.NewReplacer("{{TITLE}}", title, "{{UI}}", uiPath, "{{DOC}}", docPath+".json").Replace(swaggerUIPage)
+	router.Get(uiPath, func(c *context.Context) interface{} {
+		c.Header("Content-Type", "text/html; charset=utf-8")
+		return page
+	}).hidden = true
+	if uiDir := util.ValueOrDefault(openAPIConf["ui-dir"], "").(string); uiDir != "" {
+		router.Static(uiPath, uiDir)
+		return
+	}
+	router.staticFS(uiPath, config.AssetFileSystem(swaggerui.Assets), "Swagger UI")
+}
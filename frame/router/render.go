@@ -0,0 +1,256 @@
+package router
+
+import (
+	"io"
+	"net/http"
+	"os"
+	"reflect"
+	"strconv"
+	"sync"
+	"time"
+
+	"wataru.com/gogo/config"
+	"wataru.com/gogo/frame/context"
+	"wataru.com/gogo/frame/panics"
+	"wataru.com/gogo/json"
+	"wataru.com/gogo/logger"
+)
+
+// Renderer 将处理函数的返回结果写入响应
+type Renderer func(c *context.Context, result interface{})
+
+var (
+	renderersMu sync.RWMutex
+	// renderers 按具体类型匹配的渲染器
+	renderers = make(map[reflect.Type]Renderer)
+	// interfaceTypes 按接口匹配的渲染器类型，后注册的优先匹配
+	interfaceTypes []reflect.Type
+)
+
+// RegistRenderer 注册返回结果的渲染器，t为具体类型或接口类型
+//
+//	router.RegistRenderer(reflect.TypeOf(&Excel{}), renderExcel)
+//	router.RegistRenderer(reflect.TypeOf((*fmt.Stringer)(nil)).Elem(), renderStringer)
+//
+// 具体类型优先于接口类型，同一类型重复注册时覆盖原渲染器；渲染器panic时由 Router.PanicHandler 设置的处理函数生成响应结果
+func RegistRenderer(t reflect.Type, renderer Renderer) {
+	renderersMu.Lock()
+	defer renderersMu.Unlock()
+	if _, ok := renderers[t]; !ok && t.Kind() == reflect.Interface {
+		interfaceTypes = append(interfaceTypes, t)
+	}
+	renderers[t] = renderer
+}
+
+// lookupRenderer 查找返回结果类型对应的渲染器
+func lookupRenderer(t reflect.Type) Renderer {
+	renderersMu.RLock()
+	defer renderersMu.RUnlock()
+	if renderer, ok := renderers[t]; ok {
+		return renderer
+	}
+	for i := len(interfaceTypes) - 1; i >= 0; i-- {
+		if t.Implements(interfaceTypes[i]) {
+			return renderers[interfaceTypes[i]]
+		}
+	}
+	return nil
+}
+
+// render 渲染返回结果，nil不写入响应体，未注册渲染器的类型由 Context.Success 包装后以JSON返回
+func render(c *context.Context, result interface{}) {
+	if result == nil {
+		return
+	}
+	if renderer := lookupRenderer(reflect.TypeOf(result)); renderer != nil {
+		renderer(c, result)
+		return
+	}
+	renderJSON(c, c.Success(result))
+}
+
+// writeHeader 写入状态码，status为0时使用 Context.Status 设置的值，均未设置时为200
+func writeHeader(c *context.Context, contentType string, status int) http.ResponseWriter {
+	resp := c.HttpResponse.ResponseWriter()
+	if contentType != "" && resp.Header().Get("Content-Type") == "" {
+		resp.Header().Set("Content-Type", contentType)
+	}
+	if status == 0 {
+		status = c.StatusCode()
+	}
+	if status == 0 {
+		status = http.StatusOK
+	}
+	resp.WriteHeader(status)
+	return resp
+}
+
+func renderJSON(c *context.Context, result interface{}) {
+	resp := writeHeader(c, "application/json; charset=utf-8", result.(*context.Response).Status)
+	resp.Write(json.ToJsonByte(result))
+}
+
+func renderPage(c *context.Context, result interface{}) {
+	resp := writeHeader(c, "text/html; charset=utf-8", 0)
+	resp.Write(result.(*context.PageResponse).GetBuffer().Bytes())
+}
+
+func renderString(c *context.Context, result interface{}) {
+	resp := writeHeader(c, "text/plain; charset=utf-8", 0)
+	io.WriteString(resp, result.(string))
+}
+
+func renderBytes(c *context.Context, result interface{}) {
+	resp := writeHeader(c, "application/octet-stream", 0)
+	resp.Write(result.([]byte))
+}
+
+func renderReader(c *context.Context, result interface{}) {
+	if closer, ok := result.(io.Closer); ok {
+		defer closer.Close()
+	}
+	resp := writeHeader(c, "application/octet-stream", 0)
+	if _, err := io.Copy(resp, result.(io.Reader)); err != nil {
+		logger.Error("Stream response failed: %v", err)
+	}
+}
+
+// exposedError 错误信息可返回给客户端的错误：BizPanic、参数绑定及校验错误、实现 StatusCode() int 的错误，
+// 其他错误由 Router.recoverPanic 处理，返回 server.messages.internal-error，避免泄露内部错误信息
+func exposedError(err error) bool {
+	switch err.(type) {
+	case *panics.BizPanic, *context.BindingError, *context.ValidationError, interface{ StatusCode() int }:
+		return true
+	}
+	return false
+}
+
+// renderError 错误实现 StatusCode() int 时使用其状态码，否则为500，仅渲染 exposedError 为true的错误
+func renderError(c *context.Context, result interface{}) {
+	err := result.(error)
+	logger.Error("Web exception for error: %s", err.Error())
+	status := http.StatusInternalServerError
+	if s, ok := err.(interface{ StatusCode() int }); ok {
+		status = s.StatusCode()
+	}
+	code := -1
+	if p, ok := err.(*panics.BizPanic); ok {
+		code = p.Code
+	}
+	// 参数校验失败时data为字段到提示信息的映射
+	var data interface{}
+	if v, ok := err.(*context.ValidationError); ok {
+		data = v.Fields
+	}
+	renderJSON(c, &context.Response{
+		Data:    data,
+		Code:    code,
+		Success: false,
+		Message: err.Error(),
+		Status:  status,
+	})
+}
+
+func renderStatus(c *context.Context, result interface{}) {
+	writeHeader(c, "", result.(*context.StatusResponse).Status)
+}
+
+// renderFile 文件不存在或为目录时返回404
+func renderFile(c *context.Context, result interface{}) {
+	file := result.(*context.FileResponse)
+	var f http.File
+	var err error
+	if file.FS != nil {
+		f, err = file.FS.Open(file.Name)
+	} else {
+		f, err = config.OpenFile(file.Name)
+	}
+	var fi os.FileInfo
+	if err == nil {
+		defer f.Close()
+		if fi, err = f.Stat(); err == nil && fi.IsDir() {
+			err = os.ErrNotExist
+		}
+	}
+	if err != nil {
+		logger.Error("Open file [%s] failed: %v", file.Name, err)
+		renderJSON(c, c.ErrorWithStatus(http.StatusNotFound, http.StatusText(http.StatusNotFound)))
+		return
+	}
+	resp := c.HttpResponse.ResponseWriter()
+	if file.Filename != "" {
+		resp.Header().Set("Content-Disposition", context.ContentDisposition("attachment", file.Filename))
+	}
+	http.ServeContent(resp, c.HttpRequest.Request, fi.Name(), fi.ModTime(), f)
+}
+
+// renderReaderResponse Reader实现 io.ReadSeeker 时由 http.ServeContent 处理Range请求
+func renderReaderResponse(c *context.Context, result interface{}) {
+	r := result.(*context.ReaderResponse)
+	if closer, ok := r.Reader.(io.Closer); ok {
+		defer closer.Close()
+	}
+	resp := c.HttpResponse.ResponseWriter()
+	for key, value := range r.Headers {
+		resp.Header().Set(key, value)
+	}
+	if r.ContentType != "" {
+		resp.Header().Set("Content-Type", r.ContentType)
+	}
+	if rs, ok := r.Reader.(io.ReadSeeker); ok {
+		http.ServeContent(resp, c.HttpRequest.Request, "", time.Time{}, rs)
+		return
+	}
+	if r.ContentLength >= 0 {
+		resp.Header().Set("Content-Length", strconv.FormatInt(r.ContentLength, 10))
+	}
+	writeHeader(c, "application/octet-stream", 0)
+	if _, err := io.Copy(resp, r.Reader); err != nil {
+		logger.Error("Stream response failed: %v", err)
+	}
+}
+
+// renderStream 每次调用Step后刷新缓冲区，客户端断开时结束
+func renderStream(c *context.Context, result interface{}) {
+	step := result.(*context.StreamResponse).Step
+	resp := writeHeader(c, "", 0)
+	flusher, _ := resp.(http.Flusher)
+	done := c.HttpRequest.Context().Done()
+	for {
+		select {
+		case <-done:
+			return
+		default:
+		}
+		keepOpen := step(resp)
+		if flusher != nil {
+			flusher.Flush()
+		}
+		if !keepOpen {
+			return
+		}
+	}
+}
+
+// renderSSE 建立事件流后调用Handler，客户端断开导致的错误不记录
+func renderSSE(c *context.Context, result interface{}) {
+	stream := context.NewEventStream(c)
+	defer stream.Close()
+	if err := result.(*context.SSEResponse).Handler(stream); err != nil && !stream.Closed() && err != context.ErrStreamClosed {
+		logger.Error("Event stream failed: %v", err)
+	}
+}
+
+func init() {
+	RegistRenderer(reflect.TypeOf(&context.Response{}), renderJSON)
+	RegistRenderer(reflect.TypeOf(&context.PageResponse{}), renderPage)
+	RegistRenderer(reflect.TypeOf(""), renderString)
+	RegistRenderer(reflect.TypeOf([]byte(nil)), renderBytes)
+	RegistRenderer(reflect.TypeOf((*io.Reader)(nil)).Elem(), renderReader)
+	RegistRenderer(reflect.TypeOf((*error)(nil)).Elem(), renderError)
+	RegistRenderer(reflect.TypeOf(&context.StatusResponse{}), renderStatus)
+	RegistRenderer(reflect.TypeOf(&context.FileResponse{}), renderFile)
+	RegistRenderer(reflect.TypeOf(&context.ReaderResponse{}), renderReaderResponse)
+	RegistRenderer(reflect.TypeOf(&context.StreamResponse{}), renderStream)
+	RegistRenderer(reflect.TypeOf(&context.SSEResponse{}), renderSSE)
+}
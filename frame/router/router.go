@@ -2,44 +2,96 @@ package router
 
 import (
 	"container/list"
-	"errors"
 	"fmt"
 	"net/http"
 	"reflect"
 	"runtime"
+	"sort"
 	"strings"
+	"sync"
 
+	"wataru.com/gogo/config"
 	"wataru.com/gogo/frame/context"
 	"wataru.com/gogo/frame/middleware"
 	"wataru.com/gogo/frame/panics"
 	"wataru.com/gogo/frame/servlet"
-	"wataru.com/gogo/json"
 	"wataru.com/gogo/logger"
+	"wataru.com/gogo/util"
 )
 
 type HttpMethodType string
 
 const (
-	ALL    HttpMethodType = "ALL"
-	GET    HttpMethodType = "GET"
-	POST   HttpMethodType = "POST"
-	PUT    HttpMethodType = "PUT"
-	DELETE HttpMethodType = "DELETE"
+	ALL     HttpMethodType = "ALL"
+	GET     HttpMethodType = "GET"
+	POST    HttpMethodType = "POST"
+	PUT     HttpMethodType = "PUT"
+	DELETE  HttpMethodType = "DELETE"
+	PATCH   HttpMethodType = "PATCH"
+	HEAD    HttpMethodType = "HEAD"
+	OPTIONS HttpMethodType = "OPTIONS"
 )
 
+// segmentKind 路由路径段类型
+type segmentKind int
+
+const (
+	staticSegment   segmentKind = iota // 静态段，如 /user
+	paramSegment                       // 参数段，如 /:id
+	catchAllSegment                    // 通配段，如 /*filepath，只能位于末尾
+)
+
+type segment struct {
+	kind segmentKind
+	name string // 静态段为字面值，参数段及通配段为参数名
+}
+
 type HandlerFunc struct {
+	pattern        string
+	segments       []segment
 	target         *reflect.Value
 	targetMethod   func(*context.Context) interface{}
 	targetName     string
 	httpMethodType HttpMethodType
-	middlewares    *list.List // 细粒度全局中间件
-	groups         *list.List // 路由组
+	middlewares    *list.List           // 路由最终生效的中间件 middleware.Handler
+	groups         *list.List           // 路由组
+	routeHandlers  []middleware.Handler // 路由级中间件
+	defaultStatus  int                  // 默认HTTP状态码，用于404及405处理函数
+	name           string               // 路由名称，用于反向生成URL
+	router         *Router
+	summary        string       // 接口文档摘要
+	tags           []string     // 接口文档分组标签
+	reqType        reflect.Type // 请求参数类型，用于生成接口文档
+	respType       reflect.Type // 响应数据类型，用于生成接口文档
+	hidden         bool         // 不出现在接口文档中
 }
 
 type Router struct {
-	handlers    map[string]http.Handler
-	handleFuncs map[string]*HandlerFunc
-	middlewares *list.List // 全局中间件
+	handlers         map[string]http.Handler
+	trees            map[HttpMethodType]*node // 每个HTTP方法一棵路由树
+	handleFuncs      []*HandlerFunc           // 按注册顺序保存的路由
+	maxParams        int                      // 单个路由最多的路径参数个数
+	paramsPool       sync.Pool                // 复用路径参数切片，避免查找时分配内存
+	middlewares      *list.List               // 全局中间件
+	names            map[string]*HandlerFunc  // 命名路由
+	notFound         *HandlerFunc             // 未匹配到路由
+	methodNotAllowed *HandlerFunc             // 路径存在但HTTP方法不支持
+	options          *HandlerFunc             // 路径存在但未注册OPTIONS路由，只经过全局中间件，由CORS中间件处理预检请求，否则响应204
+	panicHandler     func(c *context.Context, r interface{}) interface{}
+	messages         *Messages
+}
+
+// Messages 默认错误提示，可通过 server.messages 配置
+//
+//	server:
+//	  messages:
+//	    not-found: 请求的资源不存在
+//	    method-not-allowed: 不支持的请求方法
+//	    internal-error: 服务器异常，请联系管理员
+type Messages struct {
+	NotFound         string
+	MethodNotAllowed string
+	InternalError    string
 }
 
 type RouterGroup struct {
@@ -55,35 +107,249 @@ func (router *Router) ServeHTTP(resp http.ResponseWriter, req *http.Request) {
 		hl.ServeHTTP(resp, req)
 		return
 	}
-	if fn, ok := router.handleFuncs[urlPath]; ok {
-		if ALL != fn.httpMethodType && req.Method != string(fn.httpMethodType) {
-			http.Error(resp, "405 method '"+req.Method+"' not allowed", http.StatusMethodNotAllowed)
+	httpMethodType := HttpMethodType(req.Method)
+	params := router.getParams()
+	defer router.putParams(params)
+	if fn := router.lookup(httpMethodType, urlPath, params); fn != nil {
+		router.serve(resp, req, fn, *params)
+		return
+	}
+	// 未注册HEAD时使用GET路由处理，响应体由http.Server丢弃
+	if httpMethodType == HEAD {
+		if fn := router.lookup(GET, urlPath, params); fn != nil {
+			router.serve(resp, req, fn, *params)
 			return
 		}
-		router.serve(resp, req, fn)
+	}
+	if fn := router.lookup(ALL, urlPath, params); fn != nil {
+		router.serve(resp, req, fn, *params)
 		return
 	}
-	http.NotFound(resp, req)
+	if allow := router.allowed(urlPath); allow != "" {
+		resp.Header().Set("Allow", allow)
+		if httpMethodType == OPTIONS {
+			router.serve(resp, req, router.options, nil)
+			return
+		}
+		router.serve(resp, req, router.methodNotAllowed, nil)
+		return
+	}
+	router.serve(resp, req, router.notFound, nil)
+}
+
+// allowed 返回路径已注册的HTTP方法，用于Allow响应头，路径不存在时返回空串
+func (router *Router) allowed(urlPath string) string {
+	methods := make([]string, 0, len(router.trees)+2)
+	params := router.getParams()
+	defer router.putParams(params)
+	for httpMethodType := range router.trees {
+		if httpMethodType == ALL {
+			continue
+		}
+		if fn := router.lookup(httpMethodType, urlPath, params); fn != nil {
+			methods = append(methods, string(httpMethodType))
+		}
+	}
+	if len(methods) == 0 {
+		return ""
+	}
+	if !containsString(methods, http.MethodHead) && containsString(methods, http.MethodGet) {
+		methods = append(methods, http.MethodHead)
+	}
+	if !containsString(methods, http.MethodOptions) {
+		methods = append(methods, http.MethodOptions)
+	}
+	sort.Strings(methods)
+	return strings.Join(methods, ", ")
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// lookup 在指定HTTP方法的路由树中查找路由，路径参数写入params，查找前清空params
+func (router *Router) lookup(httpMethodType HttpMethodType, urlPath string, params *context.Params) *HandlerFunc {
+	*params = (*params)[:0]
+	root := router.trees[httpMethodType]
+	if root == nil {
+		return nil
+	}
+	return root.getValue(urlPath, params, router.maxParams)
+}
+
+// getParams 从池中取出路径参数切片，请求结束后由 putParams 放回，
+// 因此 c.Params 仅在请求处理期间有效，在请求结束后仍运行的goroutine中使用时需先复制
+func (router *Router) getParams() *context.Params {
+	if ps, ok := router.paramsPool.Get().(*context.Params); ok && cap(*ps) >= router.maxParams {
+		return ps
+	}
+	ps := make(context.Params, 0, router.maxParams)
+	return &ps
+}
+
+func (router *Router) putParams(ps *context.Params) {
+	router.paramsPool.Put(ps)
+}
+
+// parsePattern 解析路由路径，支持 /user/:id 参数段与 /static/*filepath 通配段
+func parsePattern(pattern string) []segment {
+	if len(pattern) == 0 || pattern[0] != '/' {
+		panic("Router pattern '" + pattern + "' must begin with '/'")
+	}
+	parts := strings.Split(pattern[1:], "/")
+	segments := make([]segment, len(parts))
+	for i, part := range parts {
+		switch {
+		case strings.HasPrefix(part, ":"):
+			segments[i] = segment{kind: paramSegment, name: part[1:]}
+		case strings.HasPrefix(part, "*"):
+			if i != len(parts)-1 {
+				panic("Catch-all segment must be the last segment in router pattern '" + pattern + "'")
+			}
+			segments[i] = segment{kind: catchAllSegment, name: part[1:]}
+		default:
+			if strings.ContainsAny(part, ":*") {
+				panic("Invalid segment '" + part + "' in router pattern '" + pattern + "'")
+			}
+			segments[i] = segment{kind: staticSegment, name: part}
+		}
+		if segments[i].kind != staticSegment && segments[i].name == "" {
+			panic("Parameter name required in router pattern '" + pattern + "'")
+		}
+	}
+	return segments
+}
+
+// countParams 路径参数个数
+func countParams(segments []segment) int {
+	n := 0
+	for _, seg := range segments {
+		if seg.kind != staticSegment {
+			n++
+		}
+	}
+	return n
 }
 
 // InitRouterMiddleware 初始化中间件
 func (router *Router) InitRouterMiddleware() {
+	context.RegistTemplateFunc("url", router.URL)
+	router.loadMessages()
+	router.loadBindingConfig()
+	router.loadTrustedProxies()
+	router.loadWebSocketConfig()
+	router.loadRoutesEndpoint()
+	router.loadOpenAPIEndpoint()
 	router.loadGlobalMiddleware()
+	router.notFound.middlewares = router.collectMiddleware(nil, nil)
+	router.methodNotAllowed.middlewares = router.collectMiddleware(nil, nil)
+	router.options.middlewares = router.collectMiddleware(nil, nil)
 	for _, fcs := range router.handleFuncs {
-		middlewares := router.collectMiddleware(fcs.groups)
+		middlewares := router.collectMiddleware(fcs.groups, fcs.routeHandlers)
 		fcs.middlewares = middlewares
 	}
-	router.logRouterSummary()
+	// 输出路由表时不重复记录
+	if !config.GlobalConfig.PrintRoutes {
+		router.logRouterSummary()
+	}
+}
+
+// loadMessages 读取 server.messages 配置的默认错误提示及参数校验提示
+//
+//	server:
+//	  messages:
+//	    not-found: 请求的资源不存在
+//	    locale: zh
+//	    validation:
+//	      en:
+//	        required: "{field} is required"
+func (router *Router) loadMessages() {
+	serverConf := util.ValueOrDefault((*config.GlobalConfig.Map)["server"], make(map[interface{}]interface{})).(map[interface{}]interface{})
+	messagesConf := util.ValueOrDefault(serverConf["messages"], make(map[interface{}]interface{})).(map[interface{}]interface{})
+	router.messages.NotFound = util.ValueOrDefault(messagesConf["not-found"], router.messages.NotFound).(string)
+	router.messages.MethodNotAllowed = util.ValueOrDefault(messagesConf["method-not-allowed"], router.messages.MethodNotAllowed).(string)
+	router.messages.InternalError = util.ValueOrDefault(messagesConf["internal-error"], router.messages.InternalError).(string)
+	context.DefaultValidationLocale = util.ValueOrDefault(messagesConf["locale"], context.DefaultValidationLocale).(string)
+	validationConf := util.ValueOrDefault(messagesConf["validation"], make(map[interface{}]interface{})).(map[interface{}]interface{})
+	for locale, messages := range validationConf {
+		m := make(map[string]string)
+		for tag, message := range messages.(map[interface{}]interface{}) {
+			m[fmt.Sprintf("%v", tag)] = fmt.Sprintf("%v", message)
+		}
+		context.RegistValidationMessages(fmt.Sprintf("%v", locale), m)
+	}
 }
 
-// loadGlobalMiddleware 全局中间件
+// loadBindingConfig 读取参数绑定配置，max-body-cache-size 为 Context.Body 可缓存的最大请求体字节数，
+// upload 为默认的文件上传限制，见 context.UploadLimit
+//
+//	server:
+//	  max-body-cache-size: 10485760
+//	  upload:
+//	    max-size: 104857600
+//	    max-memory: 1048576
+//	    max-files: 10
+//	    allowed-types: [application/pdf, image/*]
+func (router *Router) loadBindingConfig() {
+	serverConf := util.ValueOrDefault((*config.GlobalConfig.Map)["server"], make(map[interface{}]interface{})).(map[interface{}]interface{})
+	context.MaxBodyCacheSize = int64(util.ValueOrDefault(serverConf["max-body-cache-size"], int(context.MaxBodyCacheSize)).(int))
+	uploadConf := util.ValueOrDefault(serverConf["upload"], make(map[interface{}]interface{})).(map[interface{}]interface{})
+	limit := &context.DefaultUploadLimit
+	limit.MaxSize = int64(util.ValueOrDefault(uploadConf["max-size"], int(limit.MaxSize)).(int))
+	limit.MaxMemory = int64(util.ValueOrDefault(uploadConf["max-memory"], int(limit.MaxMemory)).(int))
+	limit.MaxFiles = util.ValueOrDefault(uploadConf["max-files"], limit.MaxFiles).(int)
+	if types, ok := uploadConf["allowed-types"].([]interface{}); ok {
+		limit.AllowedTypes = make([]string, len(types))
+		for i, t := range types {
+			limit.AllowedTypes[i] = fmt.Sprintf("%v", t)
+		}
+	}
+}
+
+// loadTrustedProxies 读取可信的反向代理，见 Context.RealIP
+//
+//	server:
+//	  trusted-proxies: [127.0.0.1, 10.0.0.0/8]
+func (router *Router) loadTrustedProxies() {
+	serverConf := util.ValueOrDefault((*config.GlobalConfig.Map)["server"], make(map[interface{}]interface{})).(map[interface{}]interface{})
+	conf, _ := serverConf["trusted-proxies"].([]interface{})
+	proxies := make([]string, len(conf))
+	for i, proxy := range conf {
+		proxies[i] = fmt.Sprintf("%v", proxy)
+	}
+	trustedProxies, err := context.ParseTrustedProxies(proxies)
+	if err != nil {
+		panic(err)
+	}
+	context.TrustedProxies = trustedProxies
+}
+
+// loadGlobalMiddleware 全局中间件，由 server.middlewares 配置，未配置时使用默认中间件
 func (router *Router) loadGlobalMiddleware() {
-	router.Middleware(middleware.NewLogMiddleware())
-	router.Middleware(middleware.NewSessionMiddleware())
+	serverConf := util.ValueOrDefault((*config.GlobalConfig.Map)["server"], make(map[interface{}]interface{})).(map[interface{}]interface{})
+	names := middleware.DefaultMiddlewares
+	if conf, ok := serverConf["middlewares"].([]interface{}); ok {
+		names = make([]string, len(conf))
+		for i, name := range conf {
+			names[i] = fmt.Sprintf("%v", name)
+		}
+	}
+	for _, name := range names {
+		factory, ok := middleware.Lookup(name)
+		if !ok {
+			panic("Middleware named " + name + " does not exists!")
+		}
+		router.Use(factory())
+	}
 }
 
-// collectMiddleware 局部中间件
-func (router *Router) collectMiddleware(groups *list.List) *list.List {
+// collectMiddleware 按 全局 -> 分组(外层到内层) -> 路由 的顺序收集中间件
+func (router *Router) collectMiddleware(groups *list.List, routeHandlers []middleware.Handler) *list.List {
 	middlewares := list.New()
 	for i := router.middlewares.Front(); i != nil; i = i.Next() {
 		middlewares.PushBack(i.Value)
@@ -95,11 +361,13 @@ func (router *Router) collectMiddleware(groups *list.List) *list.List {
 			}
 		}
 	}
+	for _, h := range routeHandlers {
+		middlewares.PushBack(h)
+	}
 	return middlewares
 }
 
-func (router *Router) serve(resp http.ResponseWriter, req *http.Request, handlerFunc *HandlerFunc) {
-	middlewares := handlerFunc.middlewares
+func (router *Router) serve(resp http.ResponseWriter, req *http.Request, handlerFunc *HandlerFunc, params context.Params) {
 	httpRequest := servlet.NewHttpRequest(req)
 	httpResponse := servlet.NewHttpResponse(resp)
 	c := &context.Context{
@@ -108,53 +376,146 @@ func (router *Router) serve(resp http.ResponseWriter, req *http.Request, handler
 		LocalVars: &context.LocalVars{
 			M: make(map[string]interface{}),
 		},
+		Params:       params,
+		RoutePattern: handlerFunc.pattern,
 	}
-	for i := middlewares.Front(); i != nil; i = i.Next() {
-		i.Value.(middleware.Middleware).Before(c)
-	}
-	r, err := router.invokeTargetControllerMethod(c, handlerFunc)
-	if err != nil {
-		r = c.Error(err.Error())
+	c.Status(handlerFunc.defaultStatus)
+	defer func() {
+		if err := httpResponse.Close(); err != nil {
+			logger.Error("Close response failed: %v", err)
+		}
+	}()
+	router.runChain(c, handlerFunc)
+	result := c.Result()
+	if err, ok := result.(error); ok && !exposedError(err) {
+		result = router.recoverPanic(c, err)
 	}
-	for i := middlewares.Front(); i != nil; i = i.Next() {
-		i.Value.(middleware.Middleware).After(c)
+	router.render(c, result)
+}
+
+// render 渲染返回结果，渲染器panic时(如SSE、流式响应的处理函数)与中间件panic相同，由panic处理函数生成响应结果，
+// 响应已开始写入时客户端可能无法识别该结果
+func (router *Router) render(c *context.Context, result interface{}) {
+	defer func() {
+		if r := recover(); r != nil {
+			defer func() {
+				if r := recover(); r != nil {
+					logger.Error("Render panic result failed: %v", r)
+				}
+			}()
+			render(c, router.recoverPanic(c, r))
+		}
+	}()
+	render(c, result)
+}
+
+// runChain 执行中间件链，中间件panic时由panic处理函数生成响应结果
+func (router *Router) runChain(c *context.Context, handlerFunc *HandlerFunc) {
+	defer func() {
+		if r := recover(); r != nil {
+			c.SetResult(router.recoverPanic(c, r))
+		}
+	}()
+	var front *list.Element
+	if handlerFunc.middlewares != nil {
+		front = handlerFunc.middlewares.Front()
 	}
-	router.renderResponse(resp, r)
+	router.next(c, front, handlerFunc)
 }
 
-func (router *Router) renderResponse(resp http.ResponseWriter, result interface{}) {
-	if _, ok := result.(*context.Response); ok {
-		resp.Header().Set("Content-Type", "application/json; charset=utf-8")
-		resp.WriteHeader(http.StatusOK)
-		resp.Write(json.ToJsonByte(result))
-	} else if v, ok := result.(*context.PageResponse); ok {
-		resp.Header().Set("Content-Type", "text/html; charset=utf-8")
-		resp.WriteHeader(http.StatusOK)
-		resp.Write(v.GetBuffer().Bytes())
+// next 依次执行中间件，最内层调用目标处理函数；中间件不调用next或中断请求时链路终止
+func (router *Router) next(c *context.Context, e *list.Element, handlerFunc *HandlerFunc) {
+	if c.IsAborted() {
+		return
+	}
+	if e == nil {
+		c.SetResult(router.invokeTargetControllerMethod(c, handlerFunc))
+		return
 	}
+	e.Value.(middleware.Handler).Handle(c, func() {
+		router.next(c, e.Next(), handlerFunc)
+	})
 }
 
-func (router *Router) invokeTargetControllerMethod(c *context.Context, handlerFunc *HandlerFunc) (result interface{}, err error) {
+func (router *Router) invokeTargetControllerMethod(c *context.Context, handlerFunc *HandlerFunc) (result interface{}) {
 	defer func() {
 		if r := recover(); r != nil {
-			var msg string
-			_, e := r.(*panics.BizPanic)
-			if e {
-				msg = fmt.Sprintf("%s", r)
-			} else {
-				msg = "服务器异常，请联系管理员"
-			}
-			logger.Error("%v", r)
-			logger.Error("Web exception for error: %s", msg)
-			err = errors.New(msg)
+			result = router.recoverPanic(c, r)
 		}
 	}()
 	// method := *handlerFunc.target
 	// args := []reflect.Value{reflect.ValueOf(c)}
 	// result = method.Call(args)[0].Interface()
 	method := handlerFunc.targetMethod
-	result = method(c)
-	return result, nil
+	return method(c)
+}
+
+// recoverPanic 将panic或处理函数返回的错误转换为响应结果，BizPanic返回其提示信息，参数绑定错误按错误渲染，
+// 其他panic及错误返回 server.messages.internal-error
+func (router *Router) recoverPanic(c *context.Context, r interface{}) interface{} {
+	switch r.(type) {
+	case *context.BindingError, *context.ValidationError:
+		return r
+	}
+	logger.Error("%v", r)
+	if router.panicHandler != nil {
+		return router.panicHandler(c, r)
+	}
+	var msg string
+	var code, status int
+	if p, ok := r.(*panics.BizPanic); ok {
+		msg = p.Message
+		code = p.Code
+		status = p.Status
+	} else {
+		msg = router.messages.InternalError
+		code = -1
+		status = http.StatusInternalServerError
+	}
+	logger.Error("Web exception for error: %s", msg)
+	return &context.Response{
+		Code:    code,
+		Success: false,
+		Message: msg,
+		Status:  status,
+	}
+}
+
+// defaultNotFound 默认404处理函数
+func (router *Router) defaultNotFound(c *context.Context) interface{} {
+	return c.ErrorWithStatus(http.StatusNotFound, router.messages.NotFound)
+}
+
+// defaultMethodNotAllowed 默认405处理函数
+func (router *Router) defaultMethodNotAllowed(c *context.Context) interface{} {
+	return c.ErrorWithStatus(http.StatusMethodNotAllowed, router.messages.MethodNotAllowed)
+}
+
+// NotFound 设置未匹配到路由时的处理函数，经过全局中间件，默认状态码404
+func (router *Router) NotFound(fn func(c *context.Context) interface{}) {
+	router.notFound = newFallbackHandlerFunc(fn, http.StatusNotFound, router.notFound)
+}
+
+// MethodNotAllowed 设置HTTP方法不支持时的处理函数，经过全局中间件，默认状态码405，响应头中已设置Allow
+func (router *Router) MethodNotAllowed(fn func(c *context.Context) interface{}) {
+	router.methodNotAllowed = newFallbackHandlerFunc(fn, http.StatusMethodNotAllowed, router.methodNotAllowed)
+}
+
+// PanicHandler 设置中间件或处理函数panic时的处理函数，处理函数返回的错误不可公开时(见 exposedError)同样由其处理，返回值作为响应结果
+func (router *Router) PanicHandler(fn func(c *context.Context, r interface{}) interface{}) {
+	router.panicHandler = fn
+}
+
+func newFallbackHandlerFunc(fn func(c *context.Context) interface{}, status int, old *HandlerFunc) *HandlerFunc {
+	handlerFunc := &HandlerFunc{
+		targetMethod:  fn,
+		targetName:    runtime.FuncForPC(reflect.ValueOf(fn).Pointer()).Name(),
+		defaultStatus: status,
+	}
+	if old != nil {
+		handlerFunc.middlewares = old.middlewares
+	}
+	return handlerFunc
 }
 
 func (router *Router) Handle(pattern string, hl http.Handler) {
@@ -164,19 +525,39 @@ func (router *Router) Handle(pattern string, hl http.Handler) {
 func (router *Router) HandleFunc(
 	httpMethodType HttpMethodType,
 	pattern string,
-	fn func(c *context.Context) interface{},
-	groups *list.List) {
+	fn interface{},
+	groups *list.List,
+	routeHandlers ...middleware.Handler) *HandlerFunc {
 	// fnName := getFunctionName(fn, '/', '.')
 	// target := reflect.ValueOf(controller).MethodByName(fnName)
 	targetName := runtime.FuncForPC(reflect.ValueOf(fn).Pointer()).Name()
-	router.handleFuncs[pattern] = &HandlerFunc{
+	segments := parsePattern(pattern)
+	targetMethod, reqType, respType := adaptHandler(fn)
+	handlerFunc := &HandlerFunc{
+		pattern:        pattern,
+		segments:       segments,
 		target:         nil,
-		targetMethod:   fn,
+		targetMethod:   targetMethod,
 		targetName:     targetName,
 		httpMethodType: httpMethodType,
 		middlewares:    nil,
 		groups:         groups,
+		routeHandlers:  routeHandlers,
+		router:         router,
+		reqType:        reqType,
+		respType:       respType,
+	}
+	root := router.trees[httpMethodType]
+	if root == nil {
+		root = newTree()
+		router.trees[httpMethodType] = root
 	}
+	root.addRoute(pattern, handlerFunc)
+	if n := countParams(segments); n > router.maxParams {
+		router.maxParams = n
+	}
+	router.handleFuncs = append(router.handleFuncs, handlerFunc)
+	return handlerFunc
 }
 
 func getFunctionName(i interface{}, seps ...rune) string {
@@ -196,41 +577,60 @@ func getFunctionName(i interface{}, seps ...rune) string {
 	return ""
 }
 
-func (router *Router) logRouterSummary() {
-	for k, v := range router.handleFuncs {
-		middlewareNames := make([]string, v.middlewares.Len())
-		t := 0
-		for i := v.middlewares.Front(); i != nil; i = i.Next() {
-			mw := reflect.TypeOf(i.Value)
-			middlewareNames[t] = mw.Name()
-			t++
-		}
-		logger.Raw("%8sMapping [%-4s] [%-20s] => [%-40s] middlewares:%s", "", v.httpMethodType, k, v.targetName, middlewareNames)
-	}
+// Middleware 注册Before/After全局中间件
+func (router *Router) Middleware(mw middleware.Middleware) {
+	router.Use(middleware.Adapt(mw))
 }
 
-func (router *Router) Middleware(mw middleware.Middleware) {
-	router.middlewares.PushBack(mw)
+// Use 注册洋葱模型全局中间件
+func (router *Router) Use(h middleware.Handler) {
+	router.middlewares.PushBack(h)
 }
 
-func (router *Router) Method(httpMethodType HttpMethodType, path string, controllerFunc func(c *context.Context) interface{}) {
+// Method 路由注册，mws为仅作用于该路由的中间件
+func (router *Router) Method(httpMethodType HttpMethodType, path string, controllerFunc interface{}, mws ...middleware.Handler) *HandlerFunc {
 	// Handle("/hello/golang/", &BaseHander{})
-	router.HandleFunc(httpMethodType, path, controllerFunc, nil)
+	return router.HandleFunc(httpMethodType, path, controllerFunc, nil, mws...)
 }
 
 // All 不限制HTTP方法路由注册
-func (router *Router) All(path string, controller *interface{}, controllerFunc func(c *context.Context) interface{}) {
-	router.Method(ALL, path, controllerFunc)
+func (router *Router) All(path string, controllerFunc interface{}, mws ...middleware.Handler) *HandlerFunc {
+	return router.Method(ALL, path, controllerFunc, mws...)
+}
+
+// Get HTTP GET路由注册
+func (router *Router) Get(path string, controllerFunc interface{}, mws ...middleware.Handler) *HandlerFunc {
+	return router.Method(GET, path, controllerFunc, mws...)
+}
+
+// Post HTTP POST路由注册
+func (router *Router) Post(path string, controllerFunc interface{}, mws ...middleware.Handler) *HandlerFunc {
+	return router.Method(POST, path, controllerFunc, mws...)
+}
+
+// Put HTTP PUT路由注册
+func (router *Router) Put(path string, controllerFunc interface{}, mws ...middleware.Handler) *HandlerFunc {
+	return router.Method(PUT, path, controllerFunc, mws...)
+}
+
+// Delete HTTP DELETE路由注册
+func (router *Router) Delete(path string, controllerFunc interface{}, mws ...middleware.Handler) *HandlerFunc {
+	return router.Method(DELETE, path, controllerFunc, mws...)
+}
+
+// Patch HTTP PATCH路由注册
+func (router *Router) Patch(path string, controllerFunc interface{}, mws ...middleware.Handler) *HandlerFunc {
+	return router.Method(PATCH, path, controllerFunc, mws...)
 }
 
-// Get HTTPGET路由注册
-func (router *Router) Get(path string, controller *interface{}, controllerFunc func(c *context.Context) interface{}) {
-	router.Method(GET, path, controllerFunc)
+// Head HTTP HEAD路由注册
+func (router *Router) Head(path string, controllerFunc interface{}, mws ...middleware.Handler) *HandlerFunc {
+	return router.Method(HEAD, path, controllerFunc, mws...)
 }
 
-// Post HTTPGET路由注册
-func (router *Router) Post(path string, controller *interface{}, controllerFunc func(c *context.Context) interface{}) {
-	router.Method(POST, path, controllerFunc)
+// Options HTTP OPTIONS路由注册
+func (router *Router) Options(path string, controllerFunc interface{}, mws ...middleware.Handler) *HandlerFunc {
+	return router.Method(OPTIONS, path, controllerFunc, mws...)
 }
 
 // Group 分组路由注册
@@ -262,32 +662,79 @@ func (group *RouterGroup) Group(path string, groupFunc func(group *RouterGroup))
 	groupFunc(newGroup)
 }
 
+// Middleware 注册Before/After分组中间件
 func (group *RouterGroup) Middleware(mw middleware.Middleware) {
-	group.middlewares.PushBack(mw)
+	group.Use(middleware.Adapt(mw))
 }
 
-func (group *RouterGroup) Method(httpMethodType HttpMethodType, path string, controllerFunc func(c *context.Context) interface{}) {
-	group.router.HandleFunc(httpMethodType, concatRouterPath(group.path, path), controllerFunc, group.accessors)
+// Use 注册洋葱模型分组中间件
+func (group *RouterGroup) Use(h middleware.Handler) {
+	group.middlewares.PushBack(h)
 }
 
-func (group *RouterGroup) All(path string, controllerFunc func(c *context.Context) interface{}) {
-	group.Method(ALL, path, controllerFunc)
+// Method 路由注册，mws为仅作用于该路由的中间件
+func (group *RouterGroup) Method(httpMethodType HttpMethodType, path string, controllerFunc interface{}, mws ...middleware.Handler) *HandlerFunc {
+	return group.router.HandleFunc(httpMethodType, concatRouterPath(group.path, path), controllerFunc, group.accessors, mws...)
 }
 
-func (group *RouterGroup) Get(path string, controllerFunc func(c *context.Context) interface{}) {
-	group.Method(GET, path, controllerFunc)
+// All 不限制HTTP方法路由注册
+func (group *RouterGroup) All(path string, controllerFunc interface{}, mws ...middleware.Handler) *HandlerFunc {
+	return group.Method(ALL, path, controllerFunc, mws...)
 }
 
-func (group *RouterGroup) Post(path string, controllerFunc func(c *context.Context) interface{}) {
-	group.Method(POST, path, controllerFunc)
+// Get HTTP GET路由注册
+func (group *RouterGroup) Get(path string, controllerFunc interface{}, mws ...middleware.Handler) *HandlerFunc {
+	return group.Method(GET, path, controllerFunc, mws...)
+}
+
+// Post HTTP POST路由注册
+func (group *RouterGroup) Post(path string, controllerFunc interface{}, mws ...middleware.Handler) *HandlerFunc {
+	return group.Method(POST, path, controllerFunc, mws...)
+}
+
+// Put HTTP PUT路由注册
+func (group *RouterGroup) Put(path string, controllerFunc interface{}, mws ...middleware.Handler) *HandlerFunc {
+	return group.Method(PUT, path, controllerFunc, mws...)
+}
+
+// Delete HTTP DELETE路由注册
+func (group *RouterGroup) Delete(path string, controllerFunc interface{}, mws ...middleware.Handler) *HandlerFunc {
+	return group.Method(DELETE, path, controllerFunc, mws...)
+}
+
+// Patch HTTP PATCH路由注册
+func (group *RouterGroup) Patch(path string, controllerFunc interface{}, mws ...middleware.Handler) *HandlerFunc {
+	return group.Method(PATCH, path, controllerFunc, mws...)
+}
+
+// Head HTTP HEAD路由注册
+func (group *RouterGroup) Head(path string, controllerFunc interface{}, mws ...middleware.Handler) *HandlerFunc {
+	return group.Method(HEAD, path, controllerFunc, mws...)
+}
+
+// Options HTTP OPTIONS路由注册
+func (group *RouterGroup) Options(path string, controllerFunc interface{}, mws ...middleware.Handler) *HandlerFunc {
+	return group.Method(OPTIONS, path, controllerFunc, mws...)
 }
 
 func NewRouter() *Router {
-	return &Router{
+	router := &Router{
 		handlers:    make(map[string]http.Handler),
-		handleFuncs: make(map[string]*HandlerFunc),
+		trees:       make(map[HttpMethodType]*node),
+		names:       make(map[string]*HandlerFunc),
 		middlewares: list.New(),
+		messages: &Messages{
+			NotFound:         "请求的资源不存在",
+			MethodNotAllowed: "不支持的请求方法",
+			InternalError:    "服务器异常，请联系管理员",
+		},
 	}
+	router.NotFound(router.defaultNotFound)
+	router.MethodNotAllowed(router.defaultMethodNotAllowed)
+	router.options = newFallbackHandlerFunc(func(c *context.Context) interface{} {
+		return c.NoContent()
+	}, http.StatusNoContent, nil)
+	return router
 }
 
 func concatRouterPath(p1, p2 string) string {
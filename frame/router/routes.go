@@ -0,0 +1,133 @@
+package router
+
+import (
+	"container/list"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"wataru.com/gogo/config"
+	"wataru.com/gogo/frame/context"
+	"wataru.com/gogo/frame/middleware"
+	"wataru.com/gogo/logger"
+	"wataru.com/gogo/util"
+)
+
+// RouteInfo 路由信息
+type RouteInfo struct {
+	Method      string   `json:"method"`
+	Path        string   `json:"path"`
+	Name        string   `json:"name,omitempty"`
+	Handler     string   `json:"handler"`
+	Middlewares []string `json:"middlewares"`
+	Group       string   `json:"group,omitempty"` // 注册路由的分组路径
+}
+
+// Routes 返回按路径及HTTP方法排序的路由表，中间件在 InitRouterMiddleware 后才完整
+func (router *Router) Routes() []RouteInfo {
+	fns := router.sortedHandlerFuncs()
+	routes := make([]RouteInfo, len(fns))
+	for i, fn := range fns {
+		routes[i] = fn.info()
+	}
+	return routes
+}
+
+// sortedHandlerFuncs 按路径及HTTP方法排序的路由
+func (router *Router) sortedHandlerFuncs() []*HandlerFunc {
+	fns := make([]*HandlerFunc, len(router.handleFuncs))
+	copy(fns, router.handleFuncs)
+	sort.SliceStable(fns, func(i, j int) bool {
+		if fns[i].pattern != fns[j].pattern {
+			return fns[i].pattern < fns[j].pattern
+		}
+		return fns[i].httpMethodType < fns[j].httpMethodType
+	})
+	return fns
+}
+
+func (fn *HandlerFunc) info() RouteInfo {
+	middlewareNames := []string{}
+	if fn.middlewares != nil {
+		for i := fn.middlewares.Front(); i != nil; i = i.Next() {
+			middlewareNames = append(middlewareNames, middleware.Name(i.Value.(middleware.Handler)))
+		}
+	}
+	group := ""
+	if fn.groups != nil && fn.groups.Len() > 0 {
+		group = fn.groups.Back().Value.(*RouterGroup).path
+	}
+	return RouteInfo{
+		Method:      string(fn.httpMethodType),
+		Path:        fn.pattern,
+		Name:        fn.name,
+		Handler:     fn.targetName,
+		Middlewares: middlewareNames,
+		Group:       group,
+	}
+}
+
+// PrintRoutes 以表格形式输出路由表
+func (router *Router) PrintRoutes(w io.Writer) {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "METHOD\tPATH\tNAME\tHANDLER\tGROUP\tMIDDLEWARES")
+	for _, route := range router.Routes() {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", route.Method, route.Path, route.Name,
+			route.Handler, route.Group, strings.Join(route.Middlewares, " -> "))
+	}
+	tw.Flush()
+}
+
+func (router *Router) logRouterSummary() {
+	for _, route := range router.Routes() {
+		logger.Raw("%8sMapping [%-7s] [%-20s] => [%-40s] middlewares:[%s]", "",
+			route.Method, route.Path, route.Handler, strings.Join(route.Middlewares, " -> "))
+	}
+}
+
+// RoutesEndpoint 注册路由表查询接口，mws用于鉴权等
+//
+//	router.RoutesEndpoint("/admin/routes", adminAuth)
+func (router *Router) RoutesEndpoint(path string, mws ...middleware.Handler) *HandlerFunc {
+	return router.routesEndpoint(path, nil, mws...)
+}
+
+// RoutesEndpoint 在分组下注册路由表查询接口，经过分组中间件
+func (group *RouterGroup) RoutesEndpoint(path string, mws ...middleware.Handler) *HandlerFunc {
+	return group.router.routesEndpoint(concatRouterPath(group.path, path), group.accessors, mws...)
+}
+
+func (router *Router) routesEndpoint(path string, groups *list.List, mws ...middleware.Handler) *HandlerFunc {
+	fn := router.HandleFunc(GET, path, func(c *context.Context) interface{} {
+		return c.Success(router.Routes())
+	}, groups, mws...)
+	fn.targetName = "Routes"
+	fn.hidden = true
+	return fn
+}
+
+// loadRoutesEndpoint 配置 server.routes-path 时注册路由表查询接口，
+// routes-middlewares 为接口使用的命名中间件，见 middleware.Regist
+//
+//	server:
+//	  routes-path: /admin/routes
+//	  routes-middlewares: [admin-auth]
+func (router *Router) loadRoutesEndpoint() {
+	serverConf := util.ValueOrDefault((*config.GlobalConfig.Map)["server"], make(map[interface{}]interface{})).(map[interface{}]interface{})
+	routesPath := util.ValueOrDefault(serverConf["routes-path"], "").(string)
+	if routesPath == "" {
+		return
+	}
+	names, _ := serverConf["routes-middlewares"].([]interface{})
+	mws := make([]middleware.Handler, len(names))
+	for i, name := range names {
+		factory, ok := middleware.Lookup(fmt.Sprintf("%v", name))
+		if !ok {
+			panic(fmt.Sprintf("Middleware named %v does not exists!", name))
+		}
+		mws[i] = factory()
+	}
+	router.RoutesEndpoint(routesPath, mws...)
+}
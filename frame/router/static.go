@@ -0,0 +1,94 @@
+package router
+
+import (
+	"container/list"
+	"net/http"
+	"path"
+	"strings"
+
+	"wataru.com/gogo/config"
+	"wataru.com/gogo/frame/context"
+	"wataru.com/gogo/frame/middleware"
+)
+
+const staticFilepathParam = "filepath"
+
+// Static 静态文件路由注册，prefix下的请求映射到dir目录
+// 文件按 外部路径 -> ./resources/ -> 可执行文件资源 的顺序查找，支持 If-Modified-Since 及 Range 请求
+//
+//	router.Static("/static", "static")
+func (router *Router) Static(prefix, dir string, mws ...middleware.Handler) *HandlerFunc {
+	return router.static(prefix, dir, false, nil, mws)
+}
+
+// SPA 单页应用路由注册，与 Static 相同，但文件不存在时返回dir下的index.html
+//
+//	router.SPA("/", "dist")
+func (router *Router) SPA(prefix, dir string, mws ...middleware.Handler) *HandlerFunc {
+	return router.static(prefix, dir, true, nil, mws)
+}
+
+// Static 静态文件路由注册
+func (group *RouterGroup) Static(prefix, dir string, mws ...middleware.Handler) *HandlerFunc {
+	return group.router.static(concatRouterPath(group.path, prefix), dir, false, group.accessors, mws)
+}
+
+// SPA 单页应用路由注册
+func (group *RouterGroup) SPA(prefix, dir string, mws ...middleware.Handler) *HandlerFunc {
+	return group.router.static(concatRouterPath(group.path, prefix), dir, true, group.accessors, mws)
+}
+
+func (router *Router) static(prefix, dir string, spa bool, groups *list.List, mws []middleware.Handler) *HandlerFunc {
+	pattern := concatRouterPath(strings.TrimSuffix(prefix, "/"), "/*"+staticFilepathParam)
+	fn := router.HandleFunc(GET, pattern, router.staticHandler(config.ResourceFileSystem(dir), spa), groups, mws...)
+	fn.hidden = true
+	if spa {
+		fn.targetName = "SPA [" + dir + "]"
+	} else {
+		fn.targetName = "Static [" + dir + "]"
+	}
+	return fn
+}
+
+// staticFS 以文件系统提供静态文件，用于框架内置的资源
+func (router *Router) staticFS(prefix string, fs http.FileSystem, name string) *HandlerFunc {
+	pattern := concatRouterPath(strings.TrimSuffix(prefix, "/"), "/*"+staticFilepathParam)
+	fn := router.HandleFunc(GET, pattern, router.staticHandler(fs, false), nil)
+	fn.hidden = true
+	fn.targetName = "Static [" + name + "]"
+	return fn
+}
+
+// staticHandler 静态文件处理函数，文件不存在时交由NotFound处理函数处理
+// 返回 FileResponse 在中间件执行完毕后渲染，中间件设置的响应头随文件一起发送
+func (router *Router) staticHandler(fs http.FileSystem, spa bool) func(c *context.Context) interface{} {
+	return func(c *context.Context) interface{} {
+		name, err := resolveStaticFile(fs, c.Param(staticFilepathParam))
+		// 单页应用中不含扩展名的路径视为前端路由
+		if err != nil && spa && path.Ext(c.Param(staticFilepathParam)) == "" {
+			name, err = resolveStaticFile(fs, "/index.html")
+		}
+		if err != nil {
+			c.Status(http.StatusNotFound)
+			return router.notFound.targetMethod(c)
+		}
+		return c.FileFromFS(name, fs)
+	}
+}
+
+// resolveStaticFile 返回存在的文件路径，目录返回其下的index.html
+func resolveStaticFile(fs http.FileSystem, name string) (string, error) {
+	f, err := fs.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return "", err
+	}
+	if fi.IsDir() {
+		return resolveStaticFile(fs, path.Join(name, "index.html"))
+	}
+	return name, nil
+}
@@ -0,0 +1,65 @@
+package router
+
+import (
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"wataru.com/gogo/config"
+	"wataru.com/gogo/frame/context"
+)
+
+// headerMiddleware 在处理函数之后设置响应头，模拟 SessionMiddleware 设置Cookie
+type headerMiddleware struct{}
+
+func (headerMiddleware) Handle(c *context.Context, next func()) {
+	next()
+	c.HttpResponse.ResponseWriter().Header().Set("X-After", "1")
+}
+
+func TestStaticFile(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "docs"), 0750)
+	ioutil.WriteFile(filepath.Join(dir, "app.js"), []byte("console.log(1)"), 0640)
+	ioutil.WriteFile(filepath.Join(dir, "index.html"), []byte("<html>root</html>"), 0640)
+	ioutil.WriteFile(filepath.Join(dir, "docs", "index.html"), []byte("<html>docs</html>"), 0640)
+
+	config.GlobalConfig.Map = &map[string]interface{}{
+		"server": map[interface{}]interface{}{"middlewares": []interface{}{}},
+	}
+	router := NewRouter()
+	router.Static("/static", dir)
+	router.SPA("/app", dir, headerMiddleware{})
+	router.InitRouterMiddleware()
+	tests := []struct {
+		path   string
+		status int
+		body   string
+		after  bool
+	}{
+		{"/static/app.js", 200, "console.log(1)", false},
+		{"/static/docs/", 200, "<html>docs</html>", false},
+		{"/static/missing.js", 404, "", false},
+		// 中间件在文件渲染前执行完毕，设置的响应头随文件发送
+		{"/app/app.js", 200, "console.log(1)", true},
+		// 单页应用中不含扩展名的路径返回index.html
+		{"/app/user/1", 200, "<html>root</html>", true},
+		{"/app/missing.js", 404, "", true},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", tt.path, nil))
+		if w.Code != tt.status {
+			t.Errorf("GET %s responded %d, want %d", tt.path, w.Code, tt.status)
+			continue
+		}
+		if tt.body != "" && w.Body.String() != tt.body {
+			t.Errorf("GET %s body %q, want %q", tt.path, w.Body.String(), tt.body)
+		}
+		if after := w.Result().Header.Get("X-After") == "1"; after != tt.after {
+			t.Errorf("GET %s X-After header sent %v, want %v", tt.path, after, tt.after)
+		}
+	}
+}
@@ -0,0 +1,8 @@
+// Package swaggerui 内置的Swagger UI静态资源，由 server.openapi.ui-path 提供
+//
+// 资源来自 swagger-ui-dist 4.11.0 (https://github.com/swagger-api/swagger-ui)，
+// 使用 Apache License 2.0 许可，assets.go 由 go-assets-builder 生成，升级时重新生成：
+//
+//	go-assets-builder -p swaggerui -o assets.go swagger-ui-bundle.js swagger-ui.css \
+//		favicon-32x32.png favicon-16x16.png oauth2-redirect.html
+package swaggerui
@@ -0,0 +1,167 @@
+package router
+
+import (
+	"strings"
+
+	"wataru.com/gogo/frame/context"
+)
+
+type nodeType uint8
+
+const (
+	staticNode   nodeType = iota // 静态前缀节点
+	paramNode                    // 参数节点，匹配到下一个 '/' 为止
+	catchAllNode                 // 通配节点，匹配剩余全部路径（含开头的 '/'）
+)
+
+// node 压缩前缀树节点
+// 子节点匹配优先级：静态节点 > 参数节点 > 通配节点，匹配失败时回溯
+type node struct {
+	nType       nodeType
+	path        string  // 静态节点为路径片段，参数节点及通配节点为参数名
+	indices     string  // 静态子节点路径的首字节，与 children 一一对应
+	children    []*node // 静态子节点
+	paramChild  *node
+	catchAll    *node
+	handlerFunc *HandlerFunc
+}
+
+func newTree() *node {
+	return &node{nType: staticNode}
+}
+
+// addRoute 注册路由，path 为当前节点之后尚未匹配的部分
+func (n *node) addRoute(path string, fn *HandlerFunc) {
+	if path == "" {
+		if n.handlerFunc != nil {
+			panic("Router pattern '" + fn.pattern + "' conflicts with existing '" +
+				n.handlerFunc.pattern + "' for method " + string(fn.httpMethodType))
+		}
+		n.handlerFunc = fn
+		return
+	}
+	if strings.HasPrefix(path, "/*") {
+		name := path[2:]
+		if n.catchAll != nil {
+			panic("Router pattern '" + fn.pattern + "' conflicts with existing '" +
+				n.catchAll.handlerFunc.pattern + "' for method " + string(fn.httpMethodType))
+		}
+		n.catchAll = &node{nType: catchAllNode, path: name, handlerFunc: fn}
+		return
+	}
+	if path[0] == ':' {
+		end := strings.IndexByte(path, '/')
+		if end < 0 {
+			end = len(path)
+		}
+		name := path[1:end]
+		if n.paramChild == nil {
+			n.paramChild = &node{nType: paramNode, path: name}
+		} else if n.paramChild.path != name {
+			panic("Parameter ':" + name + "' in router pattern '" + fn.pattern +
+				"' conflicts with existing ':" + n.paramChild.path + "'")
+		}
+		n.paramChild.addRoute(path[end:], fn)
+		return
+	}
+	// 静态片段截止到下一个参数段或通配段之前
+	end := strings.IndexAny(path, ":*")
+	if end < 0 {
+		end = len(path)
+	} else if path[end] == '*' {
+		end--
+	}
+	static := path[:end]
+	for i := 0; i < len(n.indices); i++ {
+		if n.indices[i] != static[0] {
+			continue
+		}
+		child := n.children[i]
+		l := longestCommonPrefix(static, child.path)
+		if l < len(child.path) {
+			child.split(l)
+		}
+		child.addRoute(path[l:], fn)
+		return
+	}
+	child := &node{nType: staticNode, path: static}
+	n.indices += static[:1]
+	n.children = append(n.children, child)
+	child.addRoute(path[end:], fn)
+}
+
+// split 在 l 处拆分静态节点，原节点保留公共前缀
+func (n *node) split(l int) {
+	child := &node{
+		nType:       staticNode,
+		path:        n.path[l:],
+		indices:     n.indices,
+		children:    n.children,
+		paramChild:  n.paramChild,
+		catchAll:    n.catchAll,
+		handlerFunc: n.handlerFunc,
+	}
+	n.path = n.path[:l]
+	n.indices = child.path[:1]
+	n.children = []*node{child}
+	n.paramChild = nil
+	n.catchAll = nil
+	n.handlerFunc = nil
+}
+
+// getValue 查找路由，path 为当前节点之后尚未匹配的部分
+// 路径参数追加到 params 中，params 容量不小于 maxParams 时查找过程不分配内存，见 Router.getParams
+func (n *node) getValue(path string, params *context.Params, maxParams int) *HandlerFunc {
+	if path == "" && n.handlerFunc != nil {
+		return n.handlerFunc
+	}
+	if path != "" {
+		c := path[0]
+		for i := 0; i < len(n.indices); i++ {
+			if n.indices[i] != c {
+				continue
+			}
+			child := n.children[i]
+			if strings.HasPrefix(path, child.path) {
+				if fn := child.getValue(path[len(child.path):], params, maxParams); fn != nil {
+					return fn
+				}
+			}
+			break
+		}
+	}
+	if n.paramChild != nil {
+		end := strings.IndexByte(path, '/')
+		if end < 0 {
+			end = len(path)
+		}
+		if end > 0 {
+			saved := len(*params)
+			appendParam(params, maxParams, n.paramChild.path, path[:end])
+			if fn := n.paramChild.getValue(path[end:], params, maxParams); fn != nil {
+				return fn
+			}
+			*params = (*params)[:saved]
+		}
+	}
+	if n.catchAll != nil && path != "" && path[0] == '/' {
+		appendParam(params, maxParams, n.catchAll.path, path)
+		return n.catchAll.handlerFunc
+	}
+	return nil
+}
+
+func appendParam(params *context.Params, maxParams int, key, value string) {
+	if *params == nil {
+		*params = make(context.Params, 0, maxParams)
+	}
+	*params = append(*params, context.Param{Key: key, Value: value})
+}
+
+func longestCommonPrefix(a, b string) int {
+	i := 0
+	for i < len(a) && i < len(b) && a[i] == b[i] {
+		i++
+	}
+	return i
+}
@@ -0,0 +1,219 @@
+package router
+
+import (
+	"fmt"
+	"testing"
+
+	"wataru.com/gogo/frame/context"
+)
+
+// buildTree 按顺序注册路由，返回路由树及最多的路径参数个数
+func buildTree(patterns []string) (*node, int) {
+	root := newTree()
+	maxParams := 0
+	for _, pattern := range patterns {
+		root.addRoute(pattern, &HandlerFunc{pattern: pattern, httpMethodType: GET})
+		if n := countParams(parsePattern(pattern)); n > maxParams {
+			maxParams = n
+		}
+	}
+	return root, maxParams
+}
+
+var testPatterns = []string{
+	"/",
+	"/user/new",
+	"/user/:id",
+	"/user/:id/profile",
+	"/user/:id/posts/:post",
+	"/users",
+	"/static/*filepath",
+	"/search",
+	"/support",
+	"/src/*path",
+	"/files/:dir/*filepath",
+	"/doc/readme",
+	"/doc/:name/edit",
+}
+
+func TestTreeGetValue(t *testing.T) {
+	root, maxParams := buildTree(testPatterns)
+	tests := []struct {
+		path    string
+		pattern string // 为空表示未匹配
+		params  context.Params
+	}{
+		{"/", "/", nil},
+		{"/user/new", "/user/new", nil},
+		{"/user/123", "/user/:id", context.Params{{Key: "id", Value: "123"}}},
+		// 静态节点 new 无法匹配剩余路径时回溯到参数节点
+		{"/user/new/profile", "/user/:id/profile", context.Params{{Key: "id", Value: "new"}}},
+		{"/user/1/posts/2", "/user/:id/posts/:post", context.Params{{Key: "id", Value: "1"}, {Key: "post", Value: "2"}}},
+		{"/users", "/users", nil},
+		{"/user/", "", nil},
+		{"/user/1/unknown", "", nil},
+		{"/static/css/app.css", "/static/*filepath", context.Params{{Key: "filepath", Value: "/css/app.css"}}},
+		{"/static/", "/static/*filepath", context.Params{{Key: "filepath", Value: "/"}}},
+		{"/static", "", nil},
+		{"/search", "/search", nil},
+		{"/support", "/support", nil},
+		{"/src/a/b", "/src/*path", context.Params{{Key: "path", Value: "/a/b"}}},
+		{"/s", "", nil},
+		{"/files/img/2021/a.png", "/files/:dir/*filepath", context.Params{{Key: "dir", Value: "img"}, {Key: "filepath", Value: "/2021/a.png"}}},
+		{"/doc/readme", "/doc/readme", nil},
+		{"/doc/readme/edit", "/doc/:name/edit", context.Params{{Key: "name", Value: "readme"}}},
+		{"/doc/guide/edit", "/doc/:name/edit", context.Params{{Key: "name", Value: "guide"}}},
+		{"/nothing", "", nil},
+	}
+	for _, tt := range tests {
+		params := make(context.Params, 0, maxParams)
+		fn := root.getValue(tt.path, &params, maxParams)
+		pattern := ""
+		if fn != nil {
+			pattern = fn.pattern
+		}
+		if pattern != tt.pattern {
+			t.Errorf("getValue(%q) matched %q, want %q", tt.path, pattern, tt.pattern)
+			continue
+		}
+		if fmt.Sprint(params) != fmt.Sprint([]context.Param(tt.params)) {
+			t.Errorf("getValue(%q) params %v, want %v", tt.path, params, tt.params)
+		}
+	}
+}
+
+// 注册顺序不影响匹配优先级：静态节点 > 参数节点 > 通配节点
+func TestTreePrecedence(t *testing.T) {
+	orders := [][]string{
+		{"/api/*path", "/api/:name", "/api/status"},
+		{"/api/status", "/api/:name", "/api/*path"},
+		{"/api/:name", "/api/*path", "/api/status"},
+	}
+	for _, patterns := range orders {
+		root, maxParams := buildTree(patterns)
+		for path, want := range map[string]string{
+			"/api/status":   "/api/status",
+			"/api/stat":     "/api/:name",
+			"/api/statuses": "/api/:name",
+			"/api/a/b":      "/api/*path",
+		} {
+			params := make(context.Params, 0, maxParams)
+			if fn := root.getValue(path, &params, maxParams); fn == nil || fn.pattern != want {
+				t.Errorf("patterns %v: getValue(%q) = %v, want %q", patterns, path, fn, want)
+			}
+		}
+	}
+}
+
+// 新路由与已有静态节点部分重叠时拆分节点，拆分后原路由仍可匹配
+func TestTreeSplit(t *testing.T) {
+	root, _ := buildTree([]string{"/support", "/search", "/s", "/se"})
+	if root.children[0].path != "/s" {
+		t.Fatalf("root child path %q, want %q", root.children[0].path, "/s")
+	}
+	for _, path := range []string{"/support", "/search", "/s", "/se"} {
+		var params context.Params
+		if fn := root.getValue(path, &params, 0); fn == nil || fn.pattern != path {
+			t.Errorf("getValue(%q) = %v after split", path, fn)
+		}
+	}
+	var params context.Params
+	if fn := root.getValue("/sea", &params, 0); fn != nil {
+		t.Errorf("getValue(%q) = %q, want no match", "/sea", fn.pattern)
+	}
+}
+
+func TestTreeConflicts(t *testing.T) {
+	tests := []struct {
+		existing string
+		pattern  string
+	}{
+		{"/user/:id", "/user/:id"},
+		{"/user/:id", "/user/:uid/profile"},
+		{"/static/*filepath", "/static/*path"},
+		{"/users", "/users"},
+	}
+	for _, tt := range tests {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("registering %q after %q did not panic", tt.pattern, tt.existing)
+				}
+			}()
+			buildTree([]string{tt.existing, tt.pattern})
+		}()
+	}
+}
+
+func TestTreeLookupAllocs(t *testing.T) {
+	root, maxParams := buildTree(benchPatterns())
+	params := make(context.Params, 0, maxParams)
+	for _, path := range []string{"/api/v1/svc57/status", "/api/v1/svc57/orders/9/lines/3", "/api/v1/svc57/files/a/b.txt"} {
+		allocs := testing.AllocsPerRun(100, func() {
+			params = params[:0]
+			root.getValue(path, &params, maxParams)
+		})
+		if allocs != 0 {
+			t.Errorf("getValue(%q) allocs %v, want 0", path, allocs)
+		}
+	}
+}
+
+// benchPatterns 模拟约600个路由的网关
+func benchPatterns() []string {
+	patterns := make([]string, 0, 600)
+	for i := 0; i < 100; i++ {
+		prefix := fmt.Sprintf("/api/v1/svc%d", i)
+		patterns = append(patterns,
+			prefix+"/items",
+			prefix+"/items/:id",
+			prefix+"/items/:id/detail",
+			prefix+"/orders/:order/lines/:line",
+			prefix+"/status",
+			prefix+"/files/*filepath",
+		)
+	}
+	return patterns
+}
+
+func benchmarkTree(b *testing.B, path string) {
+	root, maxParams := buildTree(benchPatterns())
+	params := make(context.Params, 0, maxParams)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		params = params[:0]
+		if root.getValue(path, &params, maxParams) == nil {
+			b.Fatal("no match for " + path)
+		}
+	}
+}
+
+func BenchmarkTreeStatic(b *testing.B) {
+	benchmarkTree(b, "/api/v1/svc57/status")
+}
+
+func BenchmarkTreeParam(b *testing.B) {
+	benchmarkTree(b, "/api/v1/svc57/orders/9/lines/3")
+}
+
+func BenchmarkTreeCatchAll(b *testing.B) {
+	benchmarkTree(b, "/api/v1/svc57/files/a/b.txt")
+}
+
+// BenchmarkRouterLookup 与 ServeHTTP 相同，路径参数切片从池中获取
+func BenchmarkRouterLookup(b *testing.B) {
+	router := NewRouter()
+	for _, pattern := range benchPatterns() {
+		router.Get(pattern, func(c *context.Context) interface{} { return nil })
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		params := router.getParams()
+		if router.lookup(GET, "/api/v1/svc57/orders/9/lines/3", params) == nil {
+			b.Fatal("no match")
+		}
+		router.putParams(params)
+	}
+}
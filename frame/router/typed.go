@@ -0,0 +1,91 @@
+package router
+
+import (
+	"reflect"
+
+	"wataru.com/gogo/frame/context"
+)
+
+var (
+	contextType = reflect.TypeOf(&context.Context{})
+	errorType   = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+// adaptHandler 将处理函数转换为 func(*context.Context) interface{}，反射解析仅在注册时进行一次
+// 支持以下形式，Req为结构体或结构体指针，请求参数按 Context.ShouldBindRequest 自动绑定并校验：
+//
+//	func(c *context.Context) interface{}
+//	func(c *context.Context, req *Req) (*Resp, error)
+//	func(c *context.Context, req *Req) error
+//	func(c *context.Context) (*Resp, error)
+//	func(c *context.Context, req *Req) Result
+//
+// 返回的error为nil时响应 Context.Success(resp)，否则按错误渲染；单个非error返回值按渲染器处理
+func adaptHandler(fn interface{}) (target func(c *context.Context) interface{}, reqType, respType reflect.Type) {
+	if f, ok := fn.(func(c *context.Context) interface{}); ok {
+		return f, nil, nil
+	}
+	fv := reflect.ValueOf(fn)
+	ft := fv.Type()
+	if ft.Kind() != reflect.Func {
+		panic("Router handler must be a func, got " + ft.String())
+	}
+	if ft.NumIn() < 1 || ft.NumIn() > 2 || ft.In(0) != contextType {
+		panic("Router handler " + ft.String() + " must accept *context.Context as the first argument")
+	}
+	if ft.NumIn() == 2 {
+		reqType = ft.In(1)
+		if indirectType(reqType).Kind() != reflect.Struct || (reqType.Kind() == reflect.Ptr && reqType.Elem().Kind() == reflect.Ptr) {
+			panic("Router handler " + ft.String() + " request must be a struct or pointer to struct")
+		}
+	}
+	raw := false
+	switch {
+	case ft.NumOut() == 1 && ft.Out(0) == errorType:
+	case ft.NumOut() == 1:
+		raw = true
+	case ft.NumOut() == 2 && ft.Out(1) == errorType:
+		respType = ft.Out(0)
+	default:
+		panic("Router handler " + ft.String() + " must return a result, error or (resp, error)")
+	}
+	target = func(c *context.Context) interface{} {
+		args := []reflect.Value{reflect.ValueOf(c)}
+		if reqType != nil {
+			req := reflect.New(indirectType(reqType))
+			if err := bindRequest(c, req.Interface()); err != nil {
+				return err
+			}
+			if reqType.Kind() != reflect.Ptr {
+				req = req.Elem()
+			}
+			args = append(args, req)
+		}
+		out := fv.Call(args)
+		if raw {
+			return out[0].Interface()
+		}
+		if err := out[len(out)-1]; !err.IsNil() {
+			return err.Interface()
+		}
+		if len(out) == 1 || (isNilable(out[0]) && out[0].IsNil()) {
+			return c.Success(nil)
+		}
+		return c.Success(out[0].Interface())
+	}
+	return target, reqType, respType
+}
+
+func isNilable(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Slice, reflect.Interface, reflect.Func, reflect.Chan:
+		return true
+	}
+	return false
+}
+
+// bindRequest 绑定请求体、查询参数、请求头及路径参数并校验，后者覆盖前者，
+// 错误为 *context.BindingError 或 *context.ValidationError，按其状态码渲染
+func bindRequest(c *context.Context, obj interface{}) error {
+	return c.ShouldBindRequest(obj)
+}
@@ -0,0 +1,76 @@
+package router
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"wataru.com/gogo/frame/context"
+)
+
+type updateUserReq struct {
+	ID   int    `uri:"id"`
+	Page int    `form:"page,default=1"`
+	Name string `json:"name"`
+}
+
+type hiddenIDReq struct {
+	ID   int    `uri:"id" json:"-"`
+	Name string `json:"name"`
+}
+
+// serveJSON 发送请求并解析响应中的data
+func serveJSON(t *testing.T, router *Router, method, url, body string, data interface{}) {
+	t.Helper()
+	req := httptest.NewRequest(method, url, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != 200 {
+		t.Fatalf("%s %s responded %d: %s", method, url, w.Code, w.Body.String())
+	}
+	resp := context.Response{Data: data}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("%s %s invalid response %q: %v", method, url, w.Body.String(), err)
+	}
+}
+
+// 路径参数、查询参数在请求体之后绑定，请求体不能覆盖路径参数
+func TestBindRequestPrecedence(t *testing.T) {
+	router := NewRouter()
+	router.Put("/user/:id", func(c *context.Context, req *updateUserReq) (*updateUserReq, error) {
+		return req, nil
+	})
+	tests := []struct {
+		url  string
+		body string
+		want updateUserReq
+	}{
+		{"/user/7", `{"ID":1,"name":"a"}`, updateUserReq{ID: 7, Page: 1, Name: "a"}},
+		{"/user/7?page=3", `{"Page":2}`, updateUserReq{ID: 7, Page: 3}},
+		// 未传查询参数时默认值不覆盖请求体中的值
+		{"/user/7", `{"Page":2}`, updateUserReq{ID: 7, Page: 2}},
+	}
+	for _, tt := range tests {
+		var got updateUserReq
+		serveJSON(t, router, "PUT", tt.url, tt.body, &got)
+		if got != tt.want {
+			t.Errorf("PUT %s %s bound %+v, want %+v", tt.url, tt.body, got, tt.want)
+		}
+	}
+}
+
+// `uri:"id" json:"-"` 的字段只由路径参数设置
+func TestBindRequestHiddenURI(t *testing.T) {
+	router := NewRouter()
+	var got hiddenIDReq
+	router.Put("/user/:id", func(c *context.Context, req *hiddenIDReq) error {
+		got = *req
+		return nil
+	})
+	serveJSON(t, router, "PUT", "/user/7", `{"ID":1,"name":"b"}`, nil)
+	if got.ID != 7 || got.Name != "b" {
+		t.Errorf("PUT /user/7 bound %+v, want ID 7 and name b", got)
+	}
+}
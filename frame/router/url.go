@@ -0,0 +1,75 @@
+package router
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Name 设置路由名称，用于 Router.URL 及模板函数 url 反向生成URL
+//
+//	router.Get("/user/:id", userDetail).Name("user.detail")
+func (fn *HandlerFunc) Name(name string) *HandlerFunc {
+	if _, ok := fn.router.names[name]; ok {
+		panic("Router named " + name + " alrealy exists!")
+	}
+	fn.name = name
+	fn.router.names[name] = fn
+	return fn
+}
+
+// URL 根据路由名称生成URL，params为参数名与参数值交替的列表，不属于路径参数的作为查询参数
+//
+//	router.URL("user.detail", "id", 42, "tab", "profile") // /user/42?tab=profile
+func (router *Router) URL(name string, params ...interface{}) (string, error) {
+	fn, ok := router.names[name]
+	if !ok {
+		return "", errors.New("router named " + name + " does not exists")
+	}
+	if len(params)%2 != 0 {
+		return "", errors.New("router " + name + " params must be key-value pairs")
+	}
+	values := make(map[string]string, len(params)/2)
+	keys := make([]string, 0, len(params)/2)
+	for i := 0; i < len(params); i += 2 {
+		key := fmt.Sprintf("%v", params[i])
+		values[key] = fmt.Sprintf("%v", params[i+1])
+		keys = append(keys, key)
+	}
+	var sb strings.Builder
+	for _, seg := range fn.segments {
+		sb.WriteByte('/')
+		switch seg.kind {
+		case staticSegment:
+			sb.WriteString(seg.name)
+		case paramSegment:
+			value, ok := values[seg.name]
+			if !ok || value == "" {
+				return "", errors.New("router " + name + " missing param " + seg.name)
+			}
+			sb.WriteString(url.PathEscape(value))
+			delete(values, seg.name)
+		case catchAllSegment:
+			value := strings.TrimPrefix(values[seg.name], "/")
+			parts := strings.Split(value, "/")
+			for i, part := range parts {
+				parts[i] = url.PathEscape(part)
+			}
+			sb.WriteString(strings.Join(parts, "/"))
+			delete(values, seg.name)
+		}
+	}
+	query := url.Values{}
+	for _, key := range keys {
+		if value, ok := values[key]; ok {
+			query.Add(key, value)
+			delete(values, key)
+		}
+	}
+	if len(query) > 0 {
+		sb.WriteByte('?')
+		sb.WriteString(query.Encode())
+	}
+	return sb.String(), nil
+}
@@ -0,0 +1,112 @@
+package router
+
+import (
+	"container/list"
+	"fmt"
+	"reflect"
+	"runtime"
+	"time"
+
+	"wataru.com/gogo/config"
+	"wataru.com/gogo/frame/context"
+	"wataru.com/gogo/frame/middleware"
+	"wataru.com/gogo/frame/websocket"
+	"wataru.com/gogo/logger"
+	"wataru.com/gogo/util"
+)
+
+// webSocketUpgrade 中间件执行完毕后升级连接
+type webSocketUpgrade struct {
+	handler websocket.Handler
+}
+
+// WebSocket WebSocket路由注册，握手请求依次经过全局、分组及路由中间件，
+// 中间件中断请求时按普通请求响应，否则升级连接并调用handler
+//
+//	router.WebSocket("/ws/notify", func(c *context.Context, conn *websocket.Conn) {
+//		for {
+//			var msg Message
+//			if err := conn.ReadJSON(&msg); err != nil {
+//				return
+//			}
+//			conn.WriteJSON(reply(msg))
+//		}
+//	}, authMiddleware)
+func (router *Router) WebSocket(path string, handler websocket.Handler, mws ...middleware.Handler) *HandlerFunc {
+	return router.webSocket(path, handler, nil, mws)
+}
+
+// WebSocket WebSocket路由注册
+func (group *RouterGroup) WebSocket(path string, handler websocket.Handler, mws ...middleware.Handler) *HandlerFunc {
+	return group.router.webSocket(concatRouterPath(group.path, path), handler, group.accessors, mws)
+}
+
+func (router *Router) webSocket(pattern string, handler websocket.Handler, groups *list.List, mws []middleware.Handler) *HandlerFunc {
+	upgrade := &webSocketUpgrade{handler: handler}
+	fn := router.HandleFunc(GET, pattern, func(c *context.Context) interface{} {
+		return upgrade
+	}, groups, mws...)
+	fn.targetName = "WebSocket [" + runtime.FuncForPC(reflect.ValueOf(handler).Pointer()).Name() + "]"
+	fn.hidden = true
+	return fn
+}
+
+// renderWebSocket 升级连接，中间件设置的响应头(如会话Cookie)随握手响应发送，升级失败时已响应400或403
+func renderWebSocket(c *context.Context, result interface{}) {
+	conn, err := websocket.Upgrade(c, websocket.DefaultOptions, c.HttpResponse.ResponseWriter().Header())
+	if err != nil {
+		logger.Error("WebSocket upgrade failed: %v", err)
+		return
+	}
+	defer conn.Close()
+	defer func() {
+		if r := recover(); r != nil {
+			logger.Error("WebSocket handler panic: %v", r)
+		}
+	}()
+	result.(*webSocketUpgrade).handler(c, conn)
+}
+
+// loadWebSocketConfig 读取WebSocket连接参数，时间格式见 time.ParseDuration
+//
+//	server:
+//	  websocket:
+//	    read-buffer-size: 1024
+//	    write-buffer-size: 1024
+//	    write-timeout: 10s
+//	    pong-timeout: 60s
+//	    ping-interval: 50s
+//	    max-message-size: 1048576
+//	    allowed-origins: ["https://admin.example.com"]
+func (router *Router) loadWebSocketConfig() {
+	serverConf := util.ValueOrDefault((*config.GlobalConfig.Map)["server"], make(map[interface{}]interface{})).(map[interface{}]interface{})
+	wsConf := util.ValueOrDefault(serverConf["websocket"], make(map[interface{}]interface{})).(map[interface{}]interface{})
+	options := &websocket.DefaultOptions
+	options.ReadBufferSize = util.ValueOrDefault(wsConf["read-buffer-size"], options.ReadBufferSize).(int)
+	options.WriteBufferSize = util.ValueOrDefault(wsConf["write-buffer-size"], options.WriteBufferSize).(int)
+	options.MaxMessageSize = int64(util.ValueOrDefault(wsConf["max-message-size"], int(options.MaxMessageSize)).(int))
+	options.WriteTimeout = configDuration(wsConf["write-timeout"], options.WriteTimeout)
+	options.PongTimeout = configDuration(wsConf["pong-timeout"], options.PongTimeout)
+	options.PingInterval = configDuration(wsConf["ping-interval"], options.PingInterval)
+	if origins, ok := wsConf["allowed-origins"].([]interface{}); ok {
+		options.AllowedOrigins = make([]string, len(origins))
+		for i, origin := range origins {
+			options.AllowedOrigins[i] = fmt.Sprintf("%v", origin)
+		}
+	}
+}
+
+func configDuration(value interface{}, defaultValue time.Duration) time.Duration {
+	if value == nil {
+		return defaultValue
+	}
+	d, err := time.ParseDuration(fmt.Sprintf("%v", value))
+	if err != nil {
+		panic("Invalid duration " + fmt.Sprintf("%v", value) + ": " + err.Error())
+	}
+	return d
+}
+
+func init() {
+	RegistRenderer(reflect.TypeOf(&webSocketUpgrade{}), renderWebSocket)
+}
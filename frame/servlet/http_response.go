@@ -1,15 +1,41 @@
 package servlet
 
-import "net/http"
+import (
+	"io"
+	"net/http"
+)
 
 type HttpResponse struct {
 	responseWriter http.ResponseWriter
+	// closers 包装后的写入器，响应渲染完成后按包装的逆序关闭
+	closers []io.Closer
 }
 
 func (httpResponse *HttpResponse) ResponseWriter() http.ResponseWriter {
 	return httpResponse.responseWriter
 }
 
+// Wrap 替换响应写入器，用于压缩等需要处理响应体的中间件
+// writer实现 io.Closer 时在响应渲染完成后关闭，以写入缓冲的剩余数据
+func (httpResponse *HttpResponse) Wrap(writer http.ResponseWriter) {
+	httpResponse.responseWriter = writer
+	if closer, ok := writer.(io.Closer); ok {
+		httpResponse.closers = append(httpResponse.closers, closer)
+	}
+}
+
+// Close 关闭包装的写入器，由路由在响应渲染完成后调用
+func (httpResponse *HttpResponse) Close() error {
+	var err error
+	for i := len(httpResponse.closers) - 1; i >= 0; i-- {
+		if e := httpResponse.closers[i].Close(); e != nil && err == nil {
+			err = e
+		}
+	}
+	httpResponse.closers = nil
+	return err
+}
+
 func NewHttpResponse(responseWriter http.ResponseWriter) *HttpResponse {
 	return &HttpResponse{
 		responseWriter: responseWriter,
@@ -0,0 +1,247 @@
+package websocket
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	ws "github.com/gorilla/websocket"
+	"wataru.com/gogo/frame/context"
+)
+
+// Message types, see RFC 6455, section 11.8.
+const (
+	TextMessage   = ws.TextMessage
+	BinaryMessage = ws.BinaryMessage
+)
+
+// ErrConnClosed 连接已关闭
+var ErrConnClosed = errors.New("websocket connection closed")
+
+// Options WebSocket连接参数，由 server.websocket 配置
+type Options struct {
+	ReadBufferSize  int
+	WriteBufferSize int
+	// WriteTimeout 单条消息的写超时
+	WriteTimeout time.Duration
+	// PongTimeout 超过该时间未收到消息或pong时断开连接
+	PongTimeout time.Duration
+	// PingInterval 发送ping的间隔，需小于 PongTimeout
+	PingInterval time.Duration
+	// MaxMessageSize 读取消息的最大字节数，为0时不限制
+	MaxMessageSize int64
+	// AllowedOrigins 允许跨域连接的Origin，"*"为全部允许，为空时仅允许同源连接
+	AllowedOrigins []string
+}
+
+// DefaultOptions 默认连接参数
+var DefaultOptions = Options{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+	WriteTimeout:    10 * time.Second,
+	PongTimeout:     60 * time.Second,
+	PingInterval:    50 * time.Second,
+	MaxMessageSize:  1 << 20,
+}
+
+// Handler WebSocket处理函数，返回时关闭连接
+// 需持续读取消息，pong及关闭帧在读取时处理，读取出错表示连接已断开
+type Handler func(c *context.Context, conn *Conn)
+
+// Conn WebSocket连接，写入方法可在多个goroutine中并发调用
+type Conn struct {
+	conn    *ws.Conn
+	options Options
+	// Context 建立连接的请求上下文，可获取会话及路径参数
+	Context *context.Context
+
+	writeMu   sync.Mutex
+	closeOnce sync.Once
+	done      chan struct{}
+	hooksMu   sync.Mutex
+	onClose   []func()
+}
+
+// Upgrade 将HTTP请求升级为WebSocket连接，responseHeader随握手响应发送，如会话Cookie
+// 失败时已向客户端写入错误响应
+func Upgrade(c *context.Context, options Options, responseHeader http.Header) (*Conn, error) {
+	upgrader := ws.Upgrader{
+		ReadBufferSize:  options.ReadBufferSize,
+		WriteBufferSize: options.WriteBufferSize,
+		CheckOrigin:     checkOrigin(options.AllowedOrigins),
+	}
+	conn, err := upgrader.Upgrade(c.HttpResponse.ResponseWriter(), c.HttpRequest.Request, responseHeader)
+	if err != nil {
+		return nil, err
+	}
+	if options.MaxMessageSize > 0 {
+		conn.SetReadLimit(options.MaxMessageSize)
+	}
+	wc := &Conn{
+		conn:    conn,
+		options: options,
+		Context: c,
+		done:    make(chan struct{}),
+	}
+	wc.extendReadDeadline()
+	conn.SetPongHandler(func(string) error {
+		wc.extendReadDeadline()
+		return nil
+	})
+	if options.PingInterval > 0 {
+		go wc.keepAlive()
+	}
+	return wc, nil
+}
+
+func checkOrigin(allowedOrigins []string) func(r *http.Request) bool {
+	if len(allowedOrigins) == 0 {
+		// 使用默认的同源检查
+		return nil
+	}
+	return func(r *http.Request) bool {
+		origin := r.Header.Get("Origin")
+		for _, allowed := range allowedOrigins {
+			if allowed == "*" || allowed == origin {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+func (conn *Conn) extendReadDeadline() {
+	if conn.options.PongTimeout > 0 {
+		conn.conn.SetReadDeadline(time.Now().Add(conn.options.PongTimeout))
+	}
+}
+
+// keepAlive 定时发送ping，发送失败时关闭连接
+func (conn *Conn) keepAlive() {
+	ticker := time.NewTicker(conn.options.PingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := conn.conn.WriteControl(ws.PingMessage, nil, time.Now().Add(conn.options.WriteTimeout)); err != nil {
+				conn.Close()
+				return
+			}
+		case <-conn.done:
+			return
+		}
+	}
+}
+
+// ReadMessage 读取消息，收到消息时延长读超时
+func (conn *Conn) ReadMessage() (messageType int, data []byte, err error) {
+	messageType, data, err = conn.conn.ReadMessage()
+	if err == nil {
+		conn.extendReadDeadline()
+	}
+	return
+}
+
+// ReadJSON 读取JSON消息
+func (conn *Conn) ReadJSON(v interface{}) error {
+	_, data, err := conn.ReadMessage()
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// WriteMessage 发送消息
+func (conn *Conn) WriteMessage(messageType int, data []byte) error {
+	return conn.write(func() error {
+		return conn.conn.WriteMessage(messageType, data)
+	})
+}
+
+// WriteText 发送文本消息
+func (conn *Conn) WriteText(text string) error {
+	return conn.WriteMessage(TextMessage, []byte(text))
+}
+
+// WriteJSON 发送JSON消息
+func (conn *Conn) WriteJSON(v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return conn.WriteMessage(TextMessage, data)
+}
+
+func (conn *Conn) writePrepared(pm *ws.PreparedMessage) error {
+	return conn.write(func() error {
+		return conn.conn.WritePreparedMessage(pm)
+	})
+}
+
+func (conn *Conn) write(fn func() error) error {
+	conn.writeMu.Lock()
+	defer conn.writeMu.Unlock()
+	if conn.Closed() {
+		return ErrConnClosed
+	}
+	if conn.options.WriteTimeout > 0 {
+		conn.conn.SetWriteDeadline(time.Now().Add(conn.options.WriteTimeout))
+	}
+	if err := fn(); err != nil {
+		go conn.Close()
+		return err
+	}
+	return nil
+}
+
+// Done 连接关闭时关闭
+func (conn *Conn) Done() <-chan struct{} {
+	return conn.done
+}
+
+// Closed 连接是否已关闭
+func (conn *Conn) Closed() bool {
+	select {
+	case <-conn.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// OnClose 注册连接关闭时执行的函数，连接已关闭时立即执行
+func (conn *Conn) OnClose(fn func()) {
+	conn.hooksMu.Lock()
+	if conn.Closed() {
+		conn.hooksMu.Unlock()
+		fn()
+		return
+	}
+	conn.onClose = append(conn.onClose, fn)
+	conn.hooksMu.Unlock()
+}
+
+// Close 发送关闭帧并关闭连接，可重复调用
+func (conn *Conn) Close() error {
+	var err error
+	conn.closeOnce.Do(func() {
+		close(conn.done)
+		conn.conn.WriteControl(ws.CloseMessage, ws.FormatCloseMessage(ws.CloseNormalClosure, ""),
+			time.Now().Add(time.Second))
+		err = conn.conn.Close()
+		conn.hooksMu.Lock()
+		hooks := conn.onClose
+		conn.hooksMu.Unlock()
+		for _, fn := range hooks {
+			fn()
+		}
+	})
+	return err
+}
+
+// IsCloseError 是否为客户端正常关闭或离开导致的错误
+func IsCloseError(err error) bool {
+	return ws.IsCloseError(err, ws.CloseNormalClosure, ws.CloseGoingAway, ws.CloseNoStatusReceived) || errors.Is(err, ErrConnClosed)
+}
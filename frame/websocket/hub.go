@@ -0,0 +1,124 @@
+package websocket
+
+import (
+	"encoding/json"
+	"sync"
+
+	ws "github.com/gorilla/websocket"
+)
+
+// Hub 连接集合，按会话ID或自定义key分组，用于广播及向指定会话推送消息
+//
+//	var notifyHub = websocket.NewHub()
+//
+//	router.WebSocket("/ws/notify", func(c *context.Context, conn *websocket.Conn) {
+//		notifyHub.Add(conn)
+//		for {
+//			if _, _, err := conn.ReadMessage(); err != nil {
+//				return
+//			}
+//		}
+//	})
+//
+//	notifyHub.SendTo(sessionID, notice)
+type Hub struct {
+	mu    sync.RWMutex
+	conns map[*Conn][]string
+	keys  map[string]map[*Conn]struct{}
+}
+
+// NewHub ...
+func NewHub() *Hub {
+	return &Hub{
+		conns: make(map[*Conn][]string),
+		keys:  make(map[string]map[*Conn]struct{}),
+	}
+}
+
+// Add 加入连接，keys为空时使用连接的会话ID，连接关闭时自动移除
+func (hub *Hub) Add(conn *Conn, keys ...string) {
+	if len(keys) == 0 && conn.Context != nil && conn.Context.Session != nil {
+		keys = []string{conn.Context.Session.Id}
+	}
+	hub.mu.Lock()
+	if _, ok := hub.conns[conn]; ok {
+		hub.mu.Unlock()
+		return
+	}
+	hub.conns[conn] = keys
+	for _, key := range keys {
+		if hub.keys[key] == nil {
+			hub.keys[key] = make(map[*Conn]struct{})
+		}
+		hub.keys[key][conn] = struct{}{}
+	}
+	hub.mu.Unlock()
+	conn.OnClose(func() {
+		hub.Remove(conn)
+	})
+}
+
+// Remove 移除连接
+func (hub *Hub) Remove(conn *Conn) {
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+	for _, key := range hub.conns[conn] {
+		delete(hub.keys[key], conn)
+		if len(hub.keys[key]) == 0 {
+			delete(hub.keys, key)
+		}
+	}
+	delete(hub.conns, conn)
+}
+
+// Count 连接数
+func (hub *Hub) Count() int {
+	hub.mu.RLock()
+	defer hub.mu.RUnlock()
+	return len(hub.conns)
+}
+
+// Broadcast 向所有连接发送JSON消息
+func (hub *Hub) Broadcast(v interface{}) error {
+	hub.mu.RLock()
+	conns := make([]*Conn, 0, len(hub.conns))
+	for conn := range hub.conns {
+		conns = append(conns, conn)
+	}
+	hub.mu.RUnlock()
+	_, err := hub.send(conns, v)
+	return err
+}
+
+// SendTo 向key对应的连接发送JSON消息，返回发送成功的连接数
+func (hub *Hub) SendTo(key string, v interface{}) (int, error) {
+	hub.mu.RLock()
+	conns := make([]*Conn, 0, len(hub.keys[key]))
+	for conn := range hub.keys[key] {
+		conns = append(conns, conn)
+	}
+	hub.mu.RUnlock()
+	return hub.send(conns, v)
+}
+
+// send 消息只编码一次，写入失败的连接被关闭并从Hub中移除
+func (hub *Hub) send(conns []*Conn, v interface{}) (int, error) {
+	if len(conns) == 0 {
+		return 0, nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return 0, err
+	}
+	pm, err := ws.NewPreparedMessage(TextMessage, data)
+	if err != nil {
+		return 0, err
+	}
+	sent := 0
+	for _, conn := range conns {
+		if conn.writePrepared(pm) == nil {
+			sent++
+		}
+	}
+	return sent, nil
+}
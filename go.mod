@@ -3,9 +3,11 @@ module wataru.com/gogo
 go 1.15
 
 require (
+	github.com/andybalholm/brotli v1.0.6
 	github.com/go-playground/validator/v10 v10.4.1
 	github.com/go-redis/redis/v8 v8.4.0
 	github.com/google/uuid v1.1.2
+	github.com/gorilla/websocket v1.4.2
 	github.com/jessevdk/go-assets v0.0.0-20160921144138-4f4301a06e15
 	github.com/jinzhu/gorm v1.9.16
 	github.com/robfig/cron v1.2.0